@@ -0,0 +1,422 @@
+package main
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"log"
+	"math"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	bottelegram "pricegoldtoday/bot"
+)
+
+// Alert rule kinds.
+const (
+	alertAbove  = "above"  // Field rises above Threshold
+	alertBelow  = "below"  // Field falls below Threshold
+	alertMove   = "move"   // Field moves at least Threshold percent day over day
+	alertSpread = "spread" // sell minus buy widens above Threshold
+)
+
+// AlertRule is a user-defined condition on one gold type, sent to ChatID
+// when it starts to hold.
+type AlertRule struct {
+	ID        string  `json:"id"`
+	ChatID    string  `json:"chat_id"`
+	GoldType  string  `json:"gold_type"`
+	Kind      string  `json:"kind"`
+	Field     string  `json:"field,omitempty"` // buy or sell; unused for spread
+	Threshold float64 `json:"threshold"`       // VND/lượng, or percent for move
+
+	// Triggered is set once the rule fired and cleared when the value
+	// retreats past the hysteresis band, so a price hovering around the
+	// threshold alerts only once.
+	Triggered   bool      `json:"triggered"`
+	LastFiredAt time.Time `json:"last_fired_at,omitempty"`
+	CreatedAt   time.Time `json:"created_at"`
+}
+
+// alertReading is the value a rule is compared with.
+type alertReading struct {
+	Value float64
+	Date  string
+}
+
+// read extracts the rule's value from the history points, oldest first. ok is
+// false when there is not enough data.
+func (r *AlertRule) read(points []PricePoint) (alertReading, bool) {
+	if len(points) == 0 {
+		return alertReading{}, false
+	}
+	latest := points[len(points)-1]
+
+	switch r.Kind {
+	case alertSpread:
+		return alertReading{Value: latest.Sell - latest.Buy, Date: latest.Date}, true
+	case alertMove:
+		if len(points) < 2 {
+			return alertReading{}, false
+		}
+		prev := pointField(points[len(points)-2], r.Field)
+		if prev == 0 {
+			return alertReading{}, false
+		}
+		return alertReading{Value: (pointField(latest, r.Field) - prev) / prev * 100, Date: latest.Date}, true
+	default:
+		return alertReading{Value: pointField(latest, r.Field), Date: latest.Date}, true
+	}
+}
+
+// holds reports whether the condition is met by v.
+func (r *AlertRule) holds(v float64) bool {
+	switch r.Kind {
+	case alertBelow:
+		return v < r.Threshold
+	case alertMove:
+		return math.Abs(v) >= r.Threshold
+	default:
+		return v > r.Threshold
+	}
+}
+
+// cleared reports whether v has retreated far enough from the threshold to
+// re-arm a triggered rule. hysteresis is a fraction of the threshold, so a
+// 0.5% move rule re-arms below a 0.4975% move at the default 0.005.
+func (r *AlertRule) cleared(v, hysteresis float64) bool {
+	switch r.Kind {
+	case alertBelow:
+		return v > r.Threshold*(1+hysteresis)
+	case alertMove:
+		return math.Abs(v) < r.Threshold*(1-hysteresis)
+	default:
+		return v < r.Threshold*(1-hysteresis)
+	}
+}
+
+func pointField(p PricePoint, field string) float64 {
+	if field == "buy" {
+		return p.Buy
+	}
+	return p.Sell
+}
+
+// alertLabels are the strings alerts are written with in one language.
+type alertLabels struct {
+	Buy, Sell string            // field names
+	Units     map[string]string // short unit names after prices
+	// Above, Below, Move and Spread describe a reading; they take the
+	// field, the value and the threshold, except Move which takes the
+	// field and the change.
+	Above, Below, Move, Spread string
+	// RuleAbove, RuleBelow, RuleMove and RuleSpread describe a rule the way
+	// it is entered; they take the gold type, the field and the threshold.
+	RuleAbove, RuleBelow, RuleMove, RuleSpread string
+
+	Footer string // Telegram footer, takes the rule ID
+
+	Usage      string // /alert syntax error
+	BadPercent string // takes the argument
+	BadPrice   string // takes the argument
+}
+
+var alertLabelsByLocale = map[string]alertLabels{
+	"vi": {
+		Buy:        "mua",
+		Sell:       "bán",
+		Units:      map[string]string{bottelegram.UnitLuong: "tr", bottelegram.UnitChi: "nghìn/chỉ"},
+		Above:      "giá %s %s, vượt %s",
+		Below:      "giá %s %s, dưới %s",
+		Move:       "giá %s biến động %s so với hôm trước",
+		Spread:     "chênh lệch mua/bán %[2]s, trên %[3]s",
+		RuleAbove:  "%s %s > %s",
+		RuleBelow:  "%s %s < %s",
+		RuleMove:   "%s %s biến động ±%s",
+		RuleSpread: "%[1]s chênh lệch mua/bán > %[3]s",
+		Footer:     "Cảnh báo %s",
+
+		Usage:      "cú pháp: /alert <loại> buy|sell >|< <giá>, /alert <loại> buy|sell move <%>, /alert <loại> spread > <giá>",
+		BadPercent: "phần trăm %q không hợp lệ",
+		BadPrice:   "giá %q không hợp lệ",
+	},
+	"en": {
+		Buy:        "buy",
+		Sell:       "sell",
+		Units:      map[string]string{bottelegram.UnitLuong: "M VND/tael", bottelegram.UnitChi: "k VND/chi"},
+		Above:      "%s price %s, above %s",
+		Below:      "%s price %s, below %s",
+		Move:       "%s price moved %s from the previous day",
+		Spread:     "buy/sell spread %[2]s, above %[3]s",
+		RuleAbove:  "%s %s > %s",
+		RuleBelow:  "%s %s < %s",
+		RuleMove:   "%s %s moves ±%s",
+		RuleSpread: "%[1]s spread > %[3]s",
+		Footer:     "Alert %s",
+
+		Usage:      "usage: /alert <type> buy|sell >|< <price>, /alert <type> buy|sell move <%>, /alert <type> spread > <price>",
+		BadPercent: "invalid percentage %q",
+		BadPrice:   "invalid price %q",
+	},
+}
+
+// alertText writes alerts in a language and unit. Telegram alerts use the
+// chat's settings and the chat notifiers their own; webhook events use
+// defaultAlertText.
+type alertText struct {
+	locale string
+	unit   string
+}
+
+var defaultAlertText = alertText{locale: bottelegram.DefaultLocale, unit: bottelegram.UnitLuong}
+
+func alertTextFor(opts bottelegram.DigestOptions) alertText {
+	return alertText{locale: opts.Locale, unit: opts.Unit}
+}
+
+func (t alertText) labels() alertLabels {
+	if l, ok := alertLabelsByLocale[t.locale]; ok {
+		return l
+	}
+	return alertLabelsByLocale[bottelegram.DefaultLocale]
+}
+
+func (t alertText) price(v float64) string {
+	unit := t.unit
+	if !bottelegram.ValidUnit(unit) {
+		unit = bottelegram.UnitLuong
+	}
+	return bottelegram.FormatPrice(v, unit) + " " + t.labels().Units[unit]
+}
+
+func (t alertText) field(field string) string {
+	if field == "buy" {
+		return t.labels().Buy
+	}
+	return t.labels().Sell
+}
+
+// rule renders a rule the way it is entered.
+func (t alertText) rule(goldType, kind, field string, threshold float64) string {
+	l := t.labels()
+	switch kind {
+	case alertAbove:
+		return fmt.Sprintf(l.RuleAbove, goldType, t.field(field), t.price(threshold))
+	case alertBelow:
+		return fmt.Sprintf(l.RuleBelow, goldType, t.field(field), t.price(threshold))
+	case alertMove:
+		return fmt.Sprintf(l.RuleMove, goldType, t.field(field), fmt.Sprintf("%g%%", threshold))
+	default:
+		return fmt.Sprintf(l.RuleSpread, goldType, t.field(field), t.price(threshold))
+	}
+}
+
+// reading renders what a fired rule saw.
+func (t alertText) reading(kind, field string, threshold, value float64) string {
+	l := t.labels()
+	switch kind {
+	case alertAbove:
+		return fmt.Sprintf(l.Above, t.field(field), t.price(value), t.price(threshold))
+	case alertBelow:
+		return fmt.Sprintf(l.Below, t.field(field), t.price(value), t.price(threshold))
+	case alertMove:
+		return fmt.Sprintf(l.Move, t.field(field), fmt.Sprintf("%+.2f%%", value))
+	default:
+		return fmt.Sprintf(l.Spread, t.field(field), t.price(value), t.price(threshold))
+	}
+}
+
+// event renders an alert event as plain text: what happened and the rule.
+func (t alertText) event(a *AlertEvent) (message, description string) {
+	day := formatDay(a.Date, labelsFor(t.locale).DayFmt)
+	message = fmt.Sprintf("%s (%s): %s", a.Name, day, t.reading(a.Kind, a.Field, a.Threshold, a.Value))
+	return message, t.rule(a.GoldType, a.Kind, a.Field, a.Threshold)
+}
+
+// Describe renders the rule the way it is entered.
+func (r *AlertRule) Describe(t alertText) string {
+	return t.rule(r.GoldType, r.Kind, r.Field, r.Threshold)
+}
+
+// message is the Telegram HTML alert.
+func (r *AlertRule) message(t alertText, provider Provider, reading alertReading) string {
+	day := formatDay(reading.Date, labelsFor(t.locale).DayFmt)
+	return fmt.Sprintf("🔔 <b>%s</b> (%s): %s\n<i>%s</i>", bottelegram.EscapeHTML(provider.Name), day,
+		bottelegram.EscapeHTML(t.reading(r.Kind, r.Field, r.Threshold, reading.Value)), fmt.Sprintf(t.labels().Footer, r.ID))
+}
+
+// parseAlertRule parses the arguments of /alert:
+//
+//	<loại> buy|sell > <giá>
+//	<loại> buy|sell < <giá>
+//	<loại> buy|sell move <phần trăm>%
+//	<loại> spread > <giá>
+//
+// Prices are in million VND per lượng ("120" or "120tr") unless given in
+// full. Errors are written in locale.
+func parseAlertRule(args []string, locale string) (*AlertRule, error) {
+	l := alertText{locale: locale}.labels()
+	usage := l.Usage
+	if len(args) < 3 {
+		return nil, errors.New(usage)
+	}
+	provider, err := lookupGoldType(args[0], locale)
+	if err != nil {
+		return nil, err
+	}
+	rule := &AlertRule{GoldType: provider.ID}
+
+	if strings.ToLower(args[1]) == "spread" {
+		if len(args) != 4 || args[2] != ">" {
+			return nil, errors.New(usage)
+		}
+		rule.Kind = alertSpread
+		rule.Threshold, err = parseAlertPrice(args[3], l)
+		return rule, err
+	}
+
+	switch strings.ToLower(args[1]) {
+	case "buy", "mua":
+		rule.Field = "buy"
+	case "sell", "ban", "bán":
+		rule.Field = "sell"
+	default:
+		return nil, errors.New(usage)
+	}
+
+	switch {
+	case len(args) == 4 && args[2] == ">":
+		rule.Kind = alertAbove
+		rule.Threshold, err = parseAlertPrice(args[3], l)
+	case len(args) == 4 && args[2] == "<":
+		rule.Kind = alertBelow
+		rule.Threshold, err = parseAlertPrice(args[3], l)
+	case len(args) == 4 && strings.ToLower(args[2]) == "move":
+		rule.Kind = alertMove
+		pct := strings.TrimSuffix(strings.TrimLeft(args[3], "±+"), "%")
+		rule.Threshold, err = strconv.ParseFloat(pct, 64)
+		if err != nil || rule.Threshold <= 0 || rule.Threshold > 100 {
+			return nil, fmt.Errorf(l.BadPercent, args[3])
+		}
+	default:
+		return nil, errors.New(usage)
+	}
+	return rule, err
+}
+
+func parseAlertPrice(s string, l alertLabels) (float64, error) {
+	v, err := strconv.ParseFloat(strings.TrimSuffix(strings.ToLower(s), "tr"), 64)
+	if err != nil || v <= 0 {
+		return 0, fmt.Errorf(l.BadPrice, s)
+	}
+	if v < 1e5 {
+		v *= 1e6 // given in millions
+	}
+	return v, nil
+}
+
+func newAlertID() string {
+	b := make([]byte, 4)
+	rand.Read(b)
+	return hex.EncodeToString(b)
+}
+
+// alertEvaluator checks the rules of a gold type after it is crawled and
+// sends the ones that start to hold through the bot.
+type alertEvaluator struct {
+	bot        *bottelegram.Bot
+	telegram   TelegramConfig // language and unit of chats without a subscription
+	cooldown   time.Duration
+	hysteresis float64
+
+	// mu serializes evaluation with /unalert so a deleted rule is not saved
+	// back.
+	mu sync.Mutex
+}
+
+func newAlertEvaluator(bot *bottelegram.Bot, cfg AlertsConfig, telegram TelegramConfig) *alertEvaluator {
+	return &alertEvaluator{bot: bot, telegram: telegram, cooldown: cfg.Cooldown.Duration, hysteresis: cfg.Hysteresis}
+}
+
+// Evaluate is a CrawlHook.
+func (e *alertEvaluator) Evaluate(ctx context.Context, goldType string, goldPrice *GoldPrice) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	rules, err := store.ListAlerts(ctx)
+	if err != nil {
+		log.Printf("Cannot list alert rules: %v", err)
+		return
+	}
+	provider, _ := findProvider(goldType)
+	points := historyFromGoldPrice(goldPrice)
+	now := time.Now()
+
+	for _, rule := range rules {
+		if rule.GoldType != goldType {
+			continue
+		}
+		reading, ok := rule.read(points)
+		if !ok {
+			continue
+		}
+
+		switch {
+		case rule.Triggered && rule.cleared(reading.Value, e.hysteresis):
+			rule.Triggered = false
+		case !rule.Triggered && rule.holds(reading.Value):
+			if now.Sub(rule.LastFiredAt) < e.cooldown {
+				// Still cooling down; try again after the next crawl.
+				continue
+			}
+			// The rule fires whether or not the chat can be reached, so the
+			// other channels still get it and it is not retried every crawl.
+			notifiers.PublishAlert(rule, provider, reading)
+			rule.Triggered = true
+			rule.LastFiredAt = now
+
+			text := alertTextFor(chatOptions(ctx, rule.ChatID, e.telegram))
+			err := e.bot.SendMessage(rule.ChatID, rule.message(text, provider, reading))
+			switch {
+			case bottelegram.IsBlocked(err):
+				log.Printf("Chat %s blocked the bot, removing alert %s: %v", rule.ChatID, rule.ID, err)
+				if err := store.DeleteAlert(ctx, rule.ID); err != nil {
+					log.Printf("Cannot remove alert %s: %v", rule.ID, err)
+				}
+				continue
+			case err != nil:
+				log.Printf("Alert %s fired but cannot be sent to chat %s: %v", rule.ID, rule.ChatID, err)
+			default:
+				log.Printf("Alert %s (%s) sent to chat %s", rule.ID, rule.Describe(text), rule.ChatID)
+			}
+		default:
+			continue
+		}
+
+		if err := store.SaveAlert(ctx, rule); err != nil {
+			log.Printf("Cannot update alert %s: %v", rule.ID, err)
+		}
+	}
+}
+
+// Delete removes a rule owned by chatID. ok is false when there is no such
+// rule.
+func (e *alertEvaluator) Delete(ctx context.Context, chatID, id string) (ok bool, err error) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	rules, err := store.ListAlerts(ctx)
+	if err != nil {
+		return false, err
+	}
+	for _, rule := range rules {
+		if rule.ID == id && rule.ChatID == chatID {
+			return true, store.DeleteAlert(ctx, id)
+		}
+	}
+	return false, nil
+}
@@ -0,0 +1,127 @@
+package main
+
+import (
+	"strings"
+	"testing"
+
+	bottelegram "pricegoldtoday/bot"
+)
+
+func TestParseAlertRule(t *testing.T) {
+	tests := []struct {
+		args    []string
+		want    AlertRule
+		wantErr bool
+	}{
+		{args: []string{"sjc", "sell", ">", "120"}, want: AlertRule{GoldType: "sjc", Kind: alertAbove, Field: "sell", Threshold: 120e6}},
+		{args: []string{"SJC", "mua", "<", "118.5tr"}, want: AlertRule{GoldType: "sjc", Kind: alertBelow, Field: "buy", Threshold: 118.5e6}},
+		{args: []string{"doji_hn", "buy", "<", "118500000"}, want: AlertRule{GoldType: "doji_hn", Kind: alertBelow, Field: "buy", Threshold: 118.5e6}},
+		{args: []string{"sjc", "sell", "move", "±1.5%"}, want: AlertRule{GoldType: "sjc", Kind: alertMove, Field: "sell", Threshold: 1.5}},
+		{args: []string{"sjc", "spread", ">", "2"}, want: AlertRule{GoldType: "sjc", Kind: alertSpread, Threshold: 2e6}},
+		{args: []string{"sjc", "sell", ">"}, wantErr: true},
+		{args: []string{"vang_gia", "sell", ">", "120"}, wantErr: true},
+		{args: []string{"sjc", "both", ">", "120"}, wantErr: true},
+		{args: []string{"sjc", "sell", "=", "120"}, wantErr: true},
+		{args: []string{"sjc", "sell", ">", "-1"}, wantErr: true},
+		{args: []string{"sjc", "sell", "move", "0%"}, wantErr: true},
+		{args: []string{"sjc", "sell", "move", "150%"}, wantErr: true},
+		{args: []string{"sjc", "spread", "<", "2"}, wantErr: true},
+	}
+	for _, tt := range tests {
+		rule, err := parseAlertRule(tt.args, "vi")
+		if tt.wantErr {
+			if err == nil {
+				t.Errorf("parseAlertRule(%q) = %+v, want an error", tt.args, rule)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("parseAlertRule(%q): %v", tt.args, err)
+			continue
+		}
+		if *rule != tt.want {
+			t.Errorf("parseAlertRule(%q) = %+v, want %+v", tt.args, *rule, tt.want)
+		}
+	}
+}
+
+func TestParseAlertRuleLocale(t *testing.T) {
+	tests := []struct {
+		locale string
+		args   []string
+		want   string
+	}{
+		{"vi", []string{"sjc", "sell", ">", "abc"}, `giá "abc" không hợp lệ`},
+		{"en", []string{"sjc", "sell", ">", "abc"}, `invalid price "abc"`},
+		{"en", []string{"sjc", "sell", "move", "0%"}, `invalid percentage "0%"`},
+		{"en", []string{"sjc", "sell"}, "usage: /alert <type>"},
+		{"en", []string{"vang_gia", "sell", ">", "120"}, `unknown gold type "vang_gia"`},
+		{"fr", []string{"sjc", "sell"}, "cú pháp: /alert <loại>"},
+	}
+	for _, tt := range tests {
+		_, err := parseAlertRule(tt.args, tt.locale)
+		if err == nil || !strings.Contains(err.Error(), tt.want) {
+			t.Errorf("parseAlertRule(%q, %s) = %v, want an error containing %q", tt.args, tt.locale, err, tt.want)
+		}
+	}
+}
+
+func TestAlertRuleText(t *testing.T) {
+	rule := &AlertRule{ID: "ab12cd34", GoldType: "sjc", Kind: alertAbove, Field: "sell", Threshold: 120e6}
+	provider, _ := findProvider("sjc")
+	reading := alertReading{Value: 121e6, Date: "2025-03-16"}
+
+	tests := []struct {
+		opts     bottelegram.DigestOptions
+		describe string
+		message  string
+	}{
+		{bottelegram.DigestOptions{Locale: "vi", Unit: bottelegram.UnitLuong}, "sjc bán > 120.0 tr",
+			"🔔 <b>SJC</b> (16/03/2025): giá bán 121.0 tr, vượt 120.0 tr\n<i>Cảnh báo ab12cd34</i>"},
+		{bottelegram.DigestOptions{Locale: "en", Unit: bottelegram.UnitChi}, "sjc sell > 12000 k VND/chi",
+			"🔔 <b>SJC</b> (2025-03-16): sell price 12100 k VND/chi, above 12000 k VND/chi\n<i>Alert ab12cd34</i>"},
+	}
+	for _, tt := range tests {
+		text := alertTextFor(tt.opts)
+		if got := rule.Describe(text); got != tt.describe {
+			t.Errorf("%s: Describe = %q, want %q", tt.opts.Locale, got, tt.describe)
+		}
+		if got := rule.message(text, provider, reading); got != tt.message {
+			t.Errorf("%s: message = %q, want %q", tt.opts.Locale, got, tt.message)
+		}
+	}
+}
+
+func TestAlertRuleHysteresis(t *testing.T) {
+	const hysteresis = 0.005
+	tests := []struct {
+		name    string
+		rule    AlertRule
+		value   float64
+		holds   bool
+		cleared bool
+	}{
+		{"above, over", AlertRule{Kind: alertAbove, Threshold: 120e6}, 120.1e6, true, false},
+		{"above, inside the band", AlertRule{Kind: alertAbove, Threshold: 120e6}, 119.8e6, false, false},
+		{"above, past the band", AlertRule{Kind: alertAbove, Threshold: 120e6}, 119.3e6, false, true},
+		{"below, under", AlertRule{Kind: alertBelow, Threshold: 120e6}, 119.9e6, true, false},
+		{"below, inside the band", AlertRule{Kind: alertBelow, Threshold: 120e6}, 120.3e6, false, false},
+		{"below, past the band", AlertRule{Kind: alertBelow, Threshold: 120e6}, 120.7e6, false, true},
+		{"spread, past the band", AlertRule{Kind: alertSpread, Threshold: 2e6}, 1.9e6, false, true},
+		{"move, down by more", AlertRule{Kind: alertMove, Threshold: 1}, -1.2, true, false},
+		{"move, inside the band", AlertRule{Kind: alertMove, Threshold: 1}, 0.998, false, false},
+		{"move, past the band", AlertRule{Kind: alertMove, Threshold: 1}, 0.9, false, true},
+		{"small move, past the band", AlertRule{Kind: alertMove, Threshold: 0.3}, 0.1, false, true},
+		{"small move, no change", AlertRule{Kind: alertMove, Threshold: 0.5}, 0, false, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.rule.holds(tt.value); got != tt.holds {
+				t.Errorf("holds(%g) = %t, want %t", tt.value, got, tt.holds)
+			}
+			if got := tt.rule.cleared(tt.value, hysteresis); got != tt.cleared {
+				t.Errorf("cleared(%g) = %t, want %t", tt.value, got, tt.cleared)
+			}
+		})
+	}
+}
@@ -0,0 +1,136 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"net/http"
+	"time"
+
+	"pricegoldtoday/vntime"
+
+	"github.com/gorilla/mux"
+)
+
+const (
+	priceUnit     = "VND/luong"
+	priceCurrency = "VND"
+)
+
+// pointV2 is one dated price in the v2 API.
+type pointV2 struct {
+	Date     string  `json:"date"` // ISO-8601 calendar date
+	Buy      float64 `json:"buy"`
+	Sell     float64 `json:"sell"`
+	Spread   float64 `json:"spread"`
+	Unit     string  `json:"unit"`
+	Currency string  `json:"currency"`
+	Source   string  `json:"source,omitempty"`
+}
+
+// seriesV2 is the v2 response for one gold type.
+type seriesV2 struct {
+	Type      string    `json:"type"`
+	Name      string    `json:"name"`
+	City      string    `json:"city"`
+	Brand     string    `json:"brand"`
+	Source    string    `json:"source"`
+	Unit      string    `json:"unit"`
+	Currency  string    `json:"currency"`
+	UpdatedAt time.Time `json:"updated_at"`
+	From      string    `json:"from,omitempty"`
+	To        string    `json:"to,omitempty"`
+	Count     int       `json:"count"`
+	Points    []pointV2 `json:"points"`
+}
+
+func buildSeriesV2(goldType string, goldPrice *GoldPrice, query seriesQuery) (*seriesV2, error) {
+	history, err := store.History(ctx, goldType, query.From, query.To)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load history: %w", err)
+	}
+	history = query.Apply(history)
+
+	provider, _ := findProvider(goldType)
+	res := &seriesV2{
+		Type:      goldType,
+		Name:      provider.Name,
+		City:      provider.City,
+		Brand:     provider.Brand,
+		Source:    goldPrice.Source,
+		Unit:      provider.Unit,
+		Currency:  priceCurrency,
+		UpdatedAt: goldPrice.UpdatedAt,
+		Count:     len(history),
+		Points:    make([]pointV2, 0, len(history)),
+	}
+	for _, p := range history {
+		res.Points = append(res.Points, pointV2{
+			Date:     p.Date,
+			Buy:      p.Buy,
+			Sell:     p.Sell,
+			Spread:   p.Sell - p.Buy,
+			Unit:     provider.Unit,
+			Currency: priceCurrency,
+			Source:   p.Source,
+		})
+	}
+	if len(history) > 0 {
+		res.From = history[0].Date
+		res.To = history[len(history)-1].Date
+	}
+	return res, nil
+}
+
+func getGoldPriceV2Handler(w http.ResponseWriter, r *http.Request) {
+	query, err := parseSeriesQuery(r.URL.Query(), vntime.Now())
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	goldPrices := loadGoldPrices(r.Context(), GOLDTYPES)
+	result := make([]*seriesV2, 0, len(goldPrices))
+	for _, goldType := range GOLDTYPES {
+		goldPrice, ok := goldPrices[goldType]
+		if !ok {
+			continue
+		}
+		series, err := buildSeriesV2(goldType, goldPrice, query)
+		if err != nil {
+			log.Printf("Cannot build v2 series for %s: %v", goldType, err)
+			continue
+		}
+		result = append(result, series)
+	}
+
+	if len(result) == 0 {
+		respondWithError(w, http.StatusInternalServerError, "Could not retrieve any gold prices")
+		return
+	}
+
+	respondWithJSON(w, http.StatusOK, map[string]interface{}{"data": result})
+}
+
+func getGoldPriceByTypeV2Handler(w http.ResponseWriter, r *http.Request) {
+	goldType := mux.Vars(r)["type"]
+
+	query, err := parseSeriesQuery(r.URL.Query(), vntime.Now())
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	goldPrice, err := loadGoldPrice(goldType)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	series, err := buildSeriesV2(goldType, goldPrice, query)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	respondWithJSON(w, http.StatusOK, series)
+}
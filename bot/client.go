@@ -0,0 +1,245 @@
+package bottelegram
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"strings"
+	"time"
+)
+
+const (
+	// DefaultAPIURL is the public Bot API endpoint.
+	DefaultAPIURL = "https://api.telegram.org"
+
+	defaultTimeout      = 15 * time.Second
+	defaultMaxRetries   = 3
+	defaultMaxRetryWait = time.Minute
+)
+
+// Client calls the Telegram Bot API. It waits out 429 responses for the
+// retry_after Telegram asks for, retries transient server errors, splits
+// long messages and returns *APIError for failed calls. Network errors are
+// retried only for methods that do not post to a chat, as a send may have
+// been delivered before the connection broke.
+type Client struct {
+	token        string
+	baseURL      string
+	httpClient   *http.Client
+	timeout      time.Duration
+	maxRetries   int
+	maxRetryWait time.Duration
+}
+
+// ClientOption customizes a Client.
+type ClientOption func(*Client)
+
+// WithBaseURL points the client at another Bot API server, such as a local
+// fake in tests or a self-hosted telegram-bot-api.
+func WithBaseURL(baseURL string) ClientOption {
+	return func(c *Client) { c.baseURL = strings.TrimRight(baseURL, "/") }
+}
+
+// WithHTTPClient replaces the underlying HTTP client.
+func WithHTTPClient(hc *http.Client) ClientOption {
+	return func(c *Client) { c.httpClient = hc }
+}
+
+// WithTimeout bounds each call, long polls excepted.
+func WithTimeout(timeout time.Duration) ClientOption {
+	return func(c *Client) { c.timeout = timeout }
+}
+
+// WithMaxRetries sets how many times a rate-limited or failed call is
+// retried, and the longest retry_after the client is willing to wait.
+func WithMaxRetries(retries int, maxWait time.Duration) ClientOption {
+	return func(c *Client) { c.maxRetries, c.maxRetryWait = retries, maxWait }
+}
+
+func NewClient(token string, opts ...ClientOption) *Client {
+	c := &Client{
+		token:        token,
+		baseURL:      DefaultAPIURL,
+		httpClient:   &http.Client{},
+		timeout:      defaultTimeout,
+		maxRetries:   defaultMaxRetries,
+		maxRetryWait: defaultMaxRetryWait,
+	}
+	for _, opt := range opts {
+		opt(c)
+	}
+	return c
+}
+
+// apiResponse is the envelope of every Bot API response.
+type apiResponse struct {
+	OK          bool            `json:"ok"`
+	Result      json.RawMessage `json:"result"`
+	ErrorCode   int             `json:"error_code"`
+	Description string          `json:"description"`
+	Parameters  *struct {
+		RetryAfter      int   `json:"retry_after"`
+		MigrateToChatID int64 `json:"migrate_to_chat_id"`
+	} `json:"parameters"`
+}
+
+// Call invokes method with params sent as JSON and decodes the result into
+// result, which may be nil.
+func (c *Client) Call(ctx context.Context, method string, params any, result any) error {
+	body, err := json.Marshal(params)
+	if err != nil {
+		return err
+	}
+	return c.do(ctx, method, c.timeout, result, func() (io.Reader, string) {
+		return bytes.NewReader(body), "application/json"
+	})
+}
+
+// SendMessage sends an HTML message, split into several messages when it is
+// longer than Telegram allows.
+func (c *Client) SendMessage(ctx context.Context, chatID, text string) error {
+	for _, part := range SplitMessage(text, MaxMessageLength) {
+		params := map[string]any{
+			"chat_id":    chatID,
+			"text":       part,
+			"parse_mode": "HTML",
+		}
+		if err := c.Call(ctx, "sendMessage", params, nil); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// SendPhoto uploads a PNG image with an optional HTML caption.
+func (c *Client) SendPhoto(ctx context.Context, chatID string, photo []byte, caption string) error {
+	return c.do(ctx, "sendPhoto", c.timeout*2, nil, func() (io.Reader, string) {
+		var body bytes.Buffer
+		mw := multipart.NewWriter(&body)
+		mw.WriteField("chat_id", chatID)
+		if caption != "" {
+			mw.WriteField("caption", TruncateMessage(caption, MaxCaptionLength))
+			mw.WriteField("parse_mode", "HTML")
+		}
+		part, _ := mw.CreateFormFile("photo", "chart.png")
+		part.Write(photo)
+		mw.Close()
+		return &body, mw.FormDataContentType()
+	})
+}
+
+// GetUpdates long-polls for updates after offset, holding the request open
+// for up to timeout.
+func (c *Client) GetUpdates(ctx context.Context, offset int, timeout time.Duration) ([]Update, error) {
+	params := map[string]any{
+		"offset":          offset,
+		"timeout":         int(timeout.Seconds()),
+		"allowed_updates": []string{"message"},
+	}
+	body, err := json.Marshal(params)
+	if err != nil {
+		return nil, err
+	}
+
+	var updates []Update
+	err = c.do(ctx, "getUpdates", timeout+c.timeout, &updates, func() (io.Reader, string) {
+		return bytes.NewReader(body), "application/json"
+	})
+	return updates, err
+}
+
+// do sends one API call, retrying 429 and 5xx responses, and network errors
+// unless method sends a message. newBody is called for every attempt.
+func (c *Client) do(ctx context.Context, method string, timeout time.Duration, result any, newBody func() (io.Reader, string)) error {
+	for attempt := 0; ; attempt++ {
+		err := c.attempt(ctx, method, timeout, result, newBody)
+		if err == nil || attempt >= c.maxRetries || ctx.Err() != nil {
+			return err
+		}
+
+		wait, ok := c.retryDelay(method, err, attempt)
+		if !ok {
+			return err
+		}
+		select {
+		case <-ctx.Done():
+			return err
+		case <-time.After(wait):
+		}
+	}
+}
+
+func (c *Client) attempt(ctx context.Context, method string, timeout time.Duration, result any, newBody func() (io.Reader, string)) error {
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	body, contentType := newBody()
+	req, err := http.NewRequestWithContext(ctx, "POST", c.baseURL+"/bot"+c.token+"/"+method, body)
+	if err != nil {
+		return c.redact(err)
+	}
+	req.Header.Set("Content-Type", contentType)
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return c.redact(err)
+	}
+	defer resp.Body.Close()
+
+	respBody, err := io.ReadAll(io.LimitReader(resp.Body, 10<<20))
+	if err != nil {
+		return c.redact(err)
+	}
+
+	var envelope apiResponse
+	if err := json.Unmarshal(respBody, &envelope); err != nil || !envelope.OK {
+		if resp.StatusCode == http.StatusOK && err != nil {
+			return fmt.Errorf("invalid %s response: %w", method, err)
+		}
+		return newAPIErrorFromResponse(method, resp.StatusCode, envelope, respBody)
+	}
+	if result != nil {
+		if err := json.Unmarshal(envelope.Result, result); err != nil {
+			return fmt.Errorf("invalid %s result: %w", method, err)
+		}
+	}
+	return nil
+}
+
+// retryDelay reports how long to wait before retrying a failed call of
+// method, or false if err is not worth retrying.
+func (c *Client) retryDelay(method string, err error, attempt int) (time.Duration, bool) {
+	var apiErr *APIError
+	if !errors.As(err, &apiErr) {
+		// Network error or timeout: the message may have gone out anyway,
+		// and sending it again would post it twice.
+		if strings.HasPrefix(method, "send") {
+			return 0, false
+		}
+		return backoff(attempt), true
+	}
+	switch {
+	case apiErr.StatusCode == http.StatusTooManyRequests:
+		if apiErr.RetryAfter > c.maxRetryWait {
+			return 0, false
+		}
+		return max(apiErr.RetryAfter, time.Second), true
+	case apiErr.StatusCode >= 500:
+		return backoff(attempt), true
+	}
+	return 0, false
+}
+
+func backoff(attempt int) time.Duration {
+	return time.Duration(1<<attempt) * 500 * time.Millisecond
+}
+
+// redact strips the token from errors that echo the request URL.
+func (c *Client) redact(err error) error {
+	return Config{TelegramBotToken: c.token}.redactError(err)
+}
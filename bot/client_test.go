@@ -0,0 +1,148 @@
+package bottelegram
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+const testToken = "123:secret"
+
+// apiReply is one canned Bot API response. A zero status drops the
+// connection instead.
+type apiReply struct {
+	status int
+	body   string
+}
+
+var (
+	okReply              = apiReply{http.StatusOK, `{"ok":true,"result":{"id":1,"username":"GiaVangBot"}}`}
+	rateLimitedReply     = apiReply{http.StatusTooManyRequests, `{"ok":false,"error_code":429,"description":"Too Many Requests: retry after 1","parameters":{"retry_after":1}}`}
+	rateLimitedLongReply = apiReply{http.StatusTooManyRequests, `{"ok":false,"error_code":429,"description":"Too Many Requests: retry after 120","parameters":{"retry_after":120}}`}
+	badGatewayReply      = apiReply{http.StatusBadGateway, `{"ok":false,"error_code":502,"description":"Bad Gateway"}`}
+	blockedReply         = apiReply{http.StatusForbidden, `{"ok":false,"error_code":403,"description":"Forbidden: bot was blocked by the user"}`}
+	droppedReply         = apiReply{}
+)
+
+// fakeAPI serves the Bot API with replies[i] for the i-th call; the last
+// reply repeats.
+func fakeAPI(t *testing.T, replies ...apiReply) (*httptest.Server, *atomic.Int32) {
+	t.Helper()
+	var calls atomic.Int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !strings.HasPrefix(r.URL.Path, "/bot"+testToken+"/") {
+			t.Errorf("unexpected path %s", r.URL.Path)
+		}
+		i := int(calls.Add(1)) - 1
+		reply := replies[min(i, len(replies)-1)]
+		if reply.status == 0 {
+			conn, _, err := w.(http.Hijacker).Hijack()
+			if err != nil {
+				t.Error(err)
+				return
+			}
+			conn.Close()
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(reply.status)
+		w.Write([]byte(reply.body))
+	}))
+	t.Cleanup(srv.Close)
+	return srv, &calls
+}
+
+func TestClientRetries(t *testing.T) {
+	tests := []struct {
+		name      string
+		replies   []apiReply
+		send      bool // sendMessage instead of getMe
+		wantCalls int32
+		wantErr   func(error) bool
+		minWait   time.Duration
+	}{
+		{"ok", []apiReply{okReply}, true, 1, nil, 0},
+		{"waits out retry_after", []apiReply{rateLimitedReply, okReply}, true, 2, nil, time.Second},
+		{"gives up on a long retry_after", []apiReply{rateLimitedLongReply}, true, 1, IsRateLimited, 0},
+		{"retries server errors", []apiReply{badGatewayReply, okReply}, false, 2, nil, 0},
+		{"does not retry blocked chats", []apiReply{blockedReply}, true, 1, IsBlocked, 0},
+		{"retries network errors on reads", []apiReply{droppedReply, okReply}, false, 2, nil, 0},
+		{"does not resend after network errors", []apiReply{droppedReply, okReply}, true, 1, func(err error) bool { return err != nil }, 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			srv, calls := fakeAPI(t, tt.replies...)
+			c := NewClient(testToken, WithBaseURL(srv.URL), WithMaxRetries(3, time.Minute))
+
+			start := time.Now()
+			var err error
+			if tt.send {
+				err = c.SendMessage(context.Background(), "42", "<b>SJC</b>")
+			} else {
+				err = c.Call(context.Background(), "getMe", map[string]any{}, nil)
+			}
+
+			if tt.wantErr == nil && err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if tt.wantErr != nil && !tt.wantErr(err) {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if got := calls.Load(); got != tt.wantCalls {
+				t.Errorf("got %d calls, want %d", got, tt.wantCalls)
+			}
+			if elapsed := time.Since(start); elapsed < tt.minWait {
+				t.Errorf("returned after %v, want at least %v", elapsed, tt.minWait)
+			}
+			if err != nil && strings.Contains(err.Error(), testToken) {
+				t.Errorf("error leaks the token: %v", err)
+			}
+		})
+	}
+}
+
+func TestGetMe(t *testing.T) {
+	srv, _ := fakeAPI(t, okReply)
+	bot, err := New(Config{TelegramBotToken: testToken, APIURL: srv.URL})
+	if err != nil {
+		t.Fatal(err)
+	}
+	me, err := bot.GetMe(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if me.Username != "GiaVangBot" {
+		t.Errorf("got username %q", me.Username)
+	}
+}
+
+func TestSendPhotoCaption(t *testing.T) {
+	captions := make(chan string, 1)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		captions <- r.FormValue("caption")
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(okReply.body))
+	}))
+	t.Cleanup(srv.Close)
+	bot, err := New(Config{TelegramBotToken: testToken, APIURL: srv.URL})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	caption := "<b>Giá vàng 30 ngày</b>: " + strings.Repeat("DOJI &lt;HN&gt;, ", 100)
+	if err := bot.SendPhoto("42", []byte("png"), caption); err != nil {
+		t.Fatal(err)
+	}
+	got := <-captions
+	if n := len([]rune(got)); n > MaxCaptionLength {
+		t.Errorf("caption has %d characters, limit %d", n, MaxCaptionLength)
+	}
+	if !strings.HasPrefix(got, "<b>Giá vàng 30 ngày</b>: DOJI &lt;HN&gt;") {
+		t.Errorf("caption starts %q", got[:60])
+	}
+	checkWhole(t, got)
+}
@@ -0,0 +1,76 @@
+package bottelegram
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+)
+
+// ErrNotConfigured is returned when the bot token is missing.
+var ErrNotConfigured = errors.New("telegram bot token is required")
+
+// Config holds the Telegram bot configuration
+type Config struct {
+	TelegramBotToken string `json:"telegram_bot_token"`
+	TelegramChatID   string `json:"telegram_chat_id"`
+	DataURL          string `json:"data_url"`
+	// APIURL overrides DefaultAPIURL, e.g. for a local fake in tests.
+	APIURL string `json:"api_url"`
+	// TemplateDir holds digest.<locale>.tmpl files overriding the built-in
+	// message templates.
+	TemplateDir string `json:"template_dir"`
+}
+
+// LoadConfigFile reads the legacy JSON bot configuration.
+func LoadConfigFile(filename string) (*Config, error) {
+	file, err := os.ReadFile(filename)
+	if err != nil {
+		return nil, err
+	}
+
+	var config Config
+	err = json.Unmarshal(file, &config)
+	if err != nil {
+		return nil, err
+	}
+
+	return &config, nil
+}
+
+func (c Config) Validate() error {
+	if c.TelegramBotToken == "" {
+		return ErrNotConfigured
+	}
+	if !strings.Contains(c.TelegramBotToken, ":") {
+		return errors.New("telegram bot token must look like <bot id>:<secret>")
+	}
+	return nil
+}
+
+// String describes the configuration with the token redacted, so a Config
+// can be logged safely.
+func (c Config) String() string {
+	return fmt.Sprintf("telegram bot %s, chat %s", RedactToken(c.TelegramBotToken), c.TelegramChatID)
+}
+
+// RedactToken keeps the public bot ID of a token and hides the secret.
+func RedactToken(token string) string {
+	if token == "" {
+		return "<unset>"
+	}
+	id, _, found := strings.Cut(token, ":")
+	if !found {
+		return "<redacted>"
+	}
+	return id + ":<redacted>"
+}
+
+// redactError strips the token from errors that echo the request URL.
+func (c Config) redactError(err error) error {
+	if err == nil || c.TelegramBotToken == "" || !strings.Contains(err.Error(), c.TelegramBotToken) {
+		return err
+	}
+	return errors.New(strings.ReplaceAll(err.Error(), c.TelegramBotToken, RedactToken(c.TelegramBotToken)))
+}
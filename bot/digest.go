@@ -0,0 +1,238 @@
+package bottelegram
+
+import (
+	"embed"
+	"fmt"
+	"math"
+	"os"
+	"path/filepath"
+	"strings"
+	"text/template"
+	"time"
+	"unicode/utf8"
+
+	"pricegoldtoday/vntime"
+)
+
+// Supported price units.
+const (
+	UnitLuong = "luong" // million VND per lượng
+	UnitChi   = "chi"   // thousand VND per chỉ (a tenth of a lượng)
+)
+
+// DefaultLocale is used for chats without a language or with one that has
+// no template.
+const DefaultLocale = "vi"
+
+var unitLabels = map[string]map[string]string{
+	UnitLuong: {"vi": "triệu đồng/lượng", "en": "million VND/tael"},
+	UnitChi:   {"vi": "nghìn đồng/chỉ", "en": "thousand VND/chi"},
+}
+
+// ValidUnit reports whether unit is UnitLuong or UnitChi.
+func ValidUnit(unit string) bool {
+	_, ok := unitLabels[unit]
+	return ok
+}
+
+// DigestOptions are the per-chat rendering preferences.
+type DigestOptions struct {
+	Locale string
+	Unit   string
+	// CompareDays is how many days back prices are compared with; 0 means
+	// yesterday.
+	CompareDays int
+}
+
+// Digest is the data given to the digest templates. Prices are already
+// formatted in the chat's unit; the raw VND/lượng values are kept on each
+// row for templates that want their own formatting.
+type Digest struct {
+	Date      time.Time // today in Vietnam
+	PrevDate  time.Time // the day compared with
+	UpdatedAt time.Time
+	Unit      string // unit label in the chat's language
+	Rows      []DigestRow
+}
+
+type DigestRow struct {
+	Type       string
+	Name       string
+	Buy        string
+	Sell       string
+	BuyChange  string // e.g. "↑0.5 (0.4%)"
+	SellChange string
+
+	BuyPrice, SellPrice         float64
+	PrevBuyPrice, PrevSellPrice float64
+}
+
+// BuildDigest compares today's prices with yesterday's, or those
+// opts.CompareDays back, for every provider.
+func BuildDigest(data *GoldPriceResponse, opts DigestOptions, now time.Time) Digest {
+	now = now.In(vntime.Location)
+	todayDate := vntime.Day(now)
+	prevDate := todayDate.AddDate(0, 0, -max(opts.CompareDays, 1))
+
+	unit := opts.Unit
+	if !ValidUnit(unit) {
+		unit = UnitLuong
+	}
+
+	digest := Digest{Date: todayDate, PrevDate: prevDate, UpdatedAt: now, Unit: UnitLabel(unit, opts.Locale)}
+	for _, p := range data.Providers {
+		row := DigestRow{Type: p.Type, Name: p.Name}
+		for i, date := range resolveDates(p, now) {
+			if i >= len(p.BuyPrices) || i >= len(p.SellPrices) {
+				break
+			}
+			switch {
+			case date.Equal(todayDate):
+				row.BuyPrice, row.SellPrice = p.BuyPrices[i], p.SellPrices[i]
+			case date.Equal(prevDate):
+				row.PrevBuyPrice, row.PrevSellPrice = p.BuyPrices[i], p.SellPrices[i]
+			}
+		}
+		row.Buy = FormatPrice(row.BuyPrice, unit)
+		row.Sell = FormatPrice(row.SellPrice, unit)
+		row.BuyChange = FormatChange(row.BuyPrice, row.PrevBuyPrice, unit)
+		row.SellChange = FormatChange(row.SellPrice, row.PrevSellPrice, unit)
+		digest.Rows = append(digest.Rows, row)
+	}
+	return digest
+}
+
+// UnitLabel names unit in locale, e.g. "triệu đồng/lượng", falling back
+// to UnitLuong and DefaultLocale.
+func UnitLabel(unit, locale string) string {
+	labels, ok := unitLabels[unit]
+	if !ok {
+		labels = unitLabels[UnitLuong]
+	}
+	if label, ok := labels[locale]; ok {
+		return label
+	}
+	return labels[DefaultLocale]
+}
+
+// UnitScale is what a VND/lượng price is divided by to express it in unit.
+func UnitScale(unit string) float64 {
+	if unit == UnitChi {
+		return 10 * 1e3
+	}
+	return 1e6
+}
+
+// FormatPrice converts a VND/lượng price to unit.
+func FormatPrice(price float64, unit string) string {
+	if unit == UnitChi {
+		return fmt.Sprintf("%.0f", price/UnitScale(unit))
+	}
+	return fmt.Sprintf("%.1f", price/UnitScale(unit))
+}
+
+// FormatChange describes the move from prev to current in unit, e.g.
+// "↑0.5 (0.4%)".
+func FormatChange(current, prev float64, unit string) string {
+	if prev == 0 {
+		return "↔ " + FormatPrice(0, unit) + " (0.0%)"
+	}
+
+	diff := current - prev
+	percent := math.Abs(diff / prev * 100)
+	switch {
+	case diff > 0:
+		return fmt.Sprintf("↑%s (%.1f%%)", FormatPrice(diff, unit), percent)
+	case diff < 0:
+		return fmt.Sprintf("↓%s (%.1f%%)", FormatPrice(-diff, unit), percent)
+	default:
+		return "↔" + FormatPrice(0, unit) + " (0.0%)"
+	}
+}
+
+//go:embed templates/*.tmpl
+var defaultTemplates embed.FS
+
+var templateFuncs = template.FuncMap{
+	// pad right-pads s with spaces to n characters, for table columns.
+	"pad": func(n int, s string) string {
+		if c := utf8.RuneCountInString(s); c < n {
+			return s + strings.Repeat(" ", n-c)
+		}
+		return s
+	},
+}
+
+// Renderer turns a Digest into an HTML message with one text/template per
+// locale, named digest.<locale>.tmpl.
+type Renderer struct {
+	templates map[string]*template.Template
+}
+
+// NewRenderer loads the built-in templates, then those in dir, which
+// replace the built-in ones for the same locale or add new locales. dir
+// may be empty.
+func NewRenderer(dir string) (*Renderer, error) {
+	r := &Renderer{templates: make(map[string]*template.Template)}
+
+	builtin, _ := defaultTemplates.ReadDir("templates")
+	for _, entry := range builtin {
+		data, err := defaultTemplates.ReadFile("templates/" + entry.Name())
+		if err != nil {
+			return nil, err
+		}
+		if err := r.add(entry.Name(), string(data)); err != nil {
+			return nil, err
+		}
+	}
+
+	if dir == "" {
+		return r, nil
+	}
+	paths, err := filepath.Glob(filepath.Join(dir, "digest.*.tmpl"))
+	if err != nil {
+		return nil, err
+	}
+	for _, path := range paths {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return nil, err
+		}
+		if err := r.add(filepath.Base(path), string(data)); err != nil {
+			return nil, err
+		}
+	}
+	return r, nil
+}
+
+func (r *Renderer) add(name, text string) error {
+	locale := strings.TrimSuffix(strings.TrimPrefix(name, "digest."), ".tmpl")
+	tmpl, err := template.New(name).Funcs(templateFuncs).Parse(text)
+	if err != nil {
+		return fmt.Errorf("invalid template %s: %w", name, err)
+	}
+	r.templates[locale] = tmpl
+	return nil
+}
+
+// Locales lists the languages with a template.
+func (r *Renderer) Locales() []string {
+	locales := make([]string, 0, len(r.templates))
+	for locale := range r.templates {
+		locales = append(locales, locale)
+	}
+	return locales
+}
+
+// Render executes the template for locale, falling back to DefaultLocale.
+func (r *Renderer) Render(locale string, digest Digest) (string, error) {
+	tmpl, ok := r.templates[locale]
+	if !ok {
+		tmpl = r.templates[DefaultLocale]
+	}
+	var sb strings.Builder
+	if err := tmpl.Execute(&sb, digest); err != nil {
+		return "", err
+	}
+	return strings.TrimSpace(sb.String()), nil
+}
@@ -0,0 +1,137 @@
+package bottelegram
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"pricegoldtoday/vntime"
+)
+
+// digestData is SJC on 15 and 16 March, with one day missing for DOJI.
+func digestData() *GoldPriceResponse {
+	return &GoldPriceResponse{Providers: []GoldPriceData{
+		{Type: "sjc", Name: "SJC", Dates: []string{"15/03", "16/03"}, BuyPrices: []float64{118e6, 118.5e6}, SellPrices: []float64{120e6, 121e6}},
+		{Type: "doji_hn", Name: "DOJI <HN>", Dates: []string{"16/03"}, BuyPrices: []float64{117e6}, SellPrices: []float64{119e6}},
+	}}
+}
+
+var digestNow = time.Date(2025, 3, 16, 10, 0, 0, 0, vntime.Location)
+
+func TestBuildDigest(t *testing.T) {
+	tests := []struct {
+		name       string
+		opts       DigestOptions
+		unit       string
+		buy, sell  string
+		buyChange  string
+		sellChange string
+	}{
+		{"luong", DigestOptions{Locale: "vi", Unit: UnitLuong}, "triệu đồng/lượng", "118.5", "121.0", "↑0.5 (0.4%)", "↑1.0 (0.8%)"},
+		{"chi", DigestOptions{Locale: "en", Unit: UnitChi}, "thousand VND/chi", "11850", "12100", "↑50 (0.4%)", "↑100 (0.8%)"},
+		{"unknown unit", DigestOptions{Locale: "vi", Unit: "kg"}, "triệu đồng/lượng", "118.5", "121.0", "↑0.5 (0.4%)", "↑1.0 (0.8%)"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			d := BuildDigest(digestData(), tt.opts, digestNow)
+			if d.Unit != tt.unit || !d.Date.Equal(vntime.Day(digestNow)) {
+				t.Errorf("unit %q, date %v", d.Unit, d.Date)
+			}
+			row := d.Rows[0]
+			if row.Buy != tt.buy || row.Sell != tt.sell || row.BuyChange != tt.buyChange || row.SellChange != tt.sellChange {
+				t.Errorf("SJC row = %+v", row)
+			}
+			if doji := d.Rows[1]; doji.PrevBuyPrice != 0 || doji.BuyPrice != 117e6 {
+				t.Errorf("DOJI row = %+v, want no previous price", doji)
+			}
+		})
+	}
+}
+
+func TestRendererLocales(t *testing.T) {
+	r, err := NewRenderer("")
+	if err != nil {
+		t.Fatal(err)
+	}
+	locales := r.Locales()
+	slices.Sort(locales)
+	if !slices.Equal(locales, []string{"en", "vi"}) {
+		t.Errorf("built-in locales = %v", locales)
+	}
+
+	tests := []struct {
+		locale string
+		opts   DigestOptions
+		want   []string
+	}{
+		{"vi", DigestOptions{Locale: "vi"}, []string{"<b>BẢNG GIÁ VÀNG NGÀY 16/03</b>", "| SJC             |  118.5 (↑0.5 (0.4%))", "Đơn vị: triệu đồng/lượng", "So sánh với ngày 15/03"}},
+		{"en", DigestOptions{Locale: "en", Unit: UnitChi}, []string{"<b>GOLD PRICES Mar 16</b>", "|  11850 (↑50 (0.4%))", "Unit: thousand VND/chi", "Compared with Mar 15"}},
+		{"fr", DigestOptions{Locale: "fr"}, []string{"<b>BẢNG GIÁ VÀNG NGÀY 16/03</b>", "triệu đồng/lượng"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.locale, func(t *testing.T) {
+			out, err := r.Render(tt.locale, BuildDigest(digestData(), tt.opts, digestNow))
+			if err != nil {
+				t.Fatal(err)
+			}
+			for _, want := range tt.want {
+				if !strings.Contains(out, want) {
+					t.Errorf("output lacks %q:\n%s", want, out)
+				}
+			}
+			if !strings.Contains(out, "DOJI &lt;HN&gt;") {
+				t.Errorf("dealer name is not escaped:\n%s", out)
+			}
+		})
+	}
+}
+
+func TestRendererTemplateDir(t *testing.T) {
+	dir := t.TempDir()
+	files := map[string]string{
+		"digest.vi.tmpl": `Giá {{range .Rows}}{{.Name}}={{.Sell}} {{end}}`,
+		"digest.ja.tmpl": `金価格 {{.Date.Format "01/02"}}`,
+		"notes.txt":      `not a template`,
+	}
+	for name, text := range files {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(text), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	r, err := NewRenderer(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	locales := r.Locales()
+	slices.Sort(locales)
+	if !slices.Equal(locales, []string{"en", "ja", "vi"}) {
+		t.Errorf("locales = %v, want the built-in ones plus ja", locales)
+	}
+
+	d := BuildDigest(digestData(), DigestOptions{}, digestNow)
+	tests := []struct{ locale, want string }{
+		{"vi", "Giá SJC=121.0 DOJI <HN>=119.0"},
+		{"ja", "金価格 03/16"},
+		{"en", "💰 <b>GOLD PRICES Mar 16</b> 💰"},
+	}
+	for _, tt := range tests {
+		out, err := r.Render(tt.locale, d)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !strings.HasPrefix(out, tt.want) {
+			t.Errorf("%s renders %q, want it to start with %q", tt.locale, out, tt.want)
+		}
+	}
+
+	if err := os.WriteFile(filepath.Join(dir, "digest.de.tmpl"), []byte(`{{.Rows`), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := NewRenderer(dir); err == nil || !strings.Contains(err.Error(), "digest.de.tmpl") {
+		t.Errorf("NewRenderer with a broken template = %v, want an error naming it", err)
+	}
+}
@@ -0,0 +1,51 @@
+package bottelegram
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+)
+
+// APIError is a failed Telegram Bot API call.
+type APIError struct {
+	Method      string
+	StatusCode  int // Telegram's error_code, or the HTTP status without one
+	Description string
+	// RetryAfter is how long Telegram asked to wait after a 429.
+	RetryAfter time.Duration
+	// MigrateToChatID is set when a group was upgraded to a supergroup.
+	MigrateToChatID int64
+}
+
+func newAPIErrorFromResponse(method string, statusCode int, resp apiResponse, body []byte) *APIError {
+	apiErr := &APIError{Method: method, StatusCode: statusCode, Description: resp.Description}
+	if resp.ErrorCode != 0 {
+		apiErr.StatusCode = resp.ErrorCode
+	}
+	if apiErr.Description == "" {
+		apiErr.Description = string(body)
+	}
+	if resp.Parameters != nil {
+		apiErr.RetryAfter = time.Duration(resp.Parameters.RetryAfter) * time.Second
+		apiErr.MigrateToChatID = resp.Parameters.MigrateToChatID
+	}
+	return apiErr
+}
+
+func (e *APIError) Error() string {
+	return fmt.Sprintf("telegram %s error %d: %s", e.Method, e.StatusCode, e.Description)
+}
+
+// IsBlocked reports whether err means the bot can no longer write to the
+// chat: it was blocked, kicked or the chat was deleted.
+func IsBlocked(err error) bool {
+	var apiErr *APIError
+	return errors.As(err, &apiErr) && apiErr.StatusCode == http.StatusForbidden
+}
+
+// IsRateLimited reports whether err is a 429 the client gave up waiting on.
+func IsRateLimited(err error) bool {
+	var apiErr *APIError
+	return errors.As(err, &apiErr) && apiErr.StatusCode == http.StatusTooManyRequests
+}
@@ -0,0 +1,111 @@
+package bottelegram
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"slices"
+	"strings"
+	"sync"
+)
+
+// Command is a parsed bot command such as "/history sjc 7".
+type Command struct {
+	Name    string   // without the leading slash or @botname suffix
+	Args    []string // whitespace-separated arguments
+	Message *Message
+}
+
+// CommandHandler answers a command with an HTML reply.
+type CommandHandler func(ctx context.Context, cmd Command) (string, error)
+
+// Router maps command names to handlers.
+type Router struct {
+	routes   map[string]CommandHandler
+	order    []string
+	notFound CommandHandler
+
+	mu       sync.RWMutex
+	username string
+}
+
+func NewRouter() *Router {
+	return &Router{routes: make(map[string]CommandHandler)}
+}
+
+// SetUsername sets the bot's own username, as returned by getMe. Commands
+// addressed to another bot, such as "/price@OtherBot" in a group, are then
+// ignored. Without it every command is answered. It is safe to call while
+// commands are being dispatched.
+func (r *Router) SetUsername(username string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.username = username
+}
+
+// Handle registers handler for /name.
+func (r *Router) Handle(name string, handler CommandHandler) {
+	if _, ok := r.routes[name]; !ok {
+		r.order = append(r.order, name)
+	}
+	r.routes[name] = handler
+}
+
+// NotFound registers the handler for commands that have none, typically a
+// hint to send /help. Without it unknown commands get no reply.
+func (r *Router) NotFound(handler CommandHandler) {
+	r.notFound = handler
+}
+
+// Commands returns the registered command names in registration order.
+func (r *Router) Commands() []string {
+	return slices.Clone(r.order)
+}
+
+// Dispatch runs the handler for a command message. ok is false when the
+// message is not a command at all or is addressed to another bot.
+func (r *Router) Dispatch(ctx context.Context, msg *Message) (reply string, ok bool) {
+	r.mu.RLock()
+	username := r.username
+	r.mu.RUnlock()
+
+	cmd, ok := parseCommand(msg, username)
+	if !ok {
+		return "", false
+	}
+
+	handler, found := r.routes[cmd.Name]
+	if !found {
+		if r.notFound == nil {
+			return "", true
+		}
+		handler = r.notFound
+	}
+
+	reply, err := handler(ctx, cmd)
+	if err != nil {
+		log.Printf("Command /%s from chat %s failed: %v", cmd.Name, msg.ChatID(), err)
+		return fmt.Sprintf("⚠️ %s", EscapeHTML(err.Error())), true
+	}
+	return reply, true
+}
+
+// parseCommand splits a command message. A "/price@GiaVangBot" suffix, used
+// in groups, must name username when it is known.
+func parseCommand(msg *Message, username string) (Command, bool) {
+	fields := strings.Fields(msg.Text)
+	if len(fields) == 0 || !strings.HasPrefix(fields[0], "/") {
+		return Command{}, false
+	}
+
+	name := strings.TrimPrefix(fields[0], "/")
+	name, target, addressed := strings.Cut(name, "@")
+	if addressed && username != "" && !strings.EqualFold(target, username) {
+		return Command{}, false
+	}
+	return Command{
+		Name:    strings.ToLower(name),
+		Args:    fields[1:],
+		Message: msg,
+	}, true
+}
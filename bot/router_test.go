@@ -0,0 +1,71 @@
+package bottelegram
+
+import (
+	"context"
+	"slices"
+	"testing"
+)
+
+func TestParseCommand(t *testing.T) {
+	tests := []struct {
+		text     string
+		username string
+		name     string
+		args     []string
+		ok       bool
+	}{
+		{text: "/price sjc", username: "GiaVangBot", name: "price", args: []string{"sjc"}, ok: true},
+		{text: "/Price@GiaVangBot sjc", username: "GiaVangBot", name: "price", args: []string{"sjc"}, ok: true},
+		{text: "/price@giavangbot", username: "GiaVangBot", name: "price", args: []string{}, ok: true},
+		{text: "/price@OtherBot sjc", username: "GiaVangBot"},
+		{text: "/price@OtherBot sjc", username: "", name: "price", args: []string{"sjc"}, ok: true},
+		{text: "  /history  sjc   7 ", username: "GiaVangBot", name: "history", args: []string{"sjc", "7"}, ok: true},
+		{text: "giá vàng hôm nay", username: "GiaVangBot"},
+		{text: "", username: "GiaVangBot"},
+	}
+	for _, tt := range tests {
+		cmd, ok := parseCommand(&Message{Text: tt.text}, tt.username)
+		if ok != tt.ok {
+			t.Errorf("parseCommand(%q, %q) ok = %t, want %t", tt.text, tt.username, ok, tt.ok)
+			continue
+		}
+		if !ok {
+			continue
+		}
+		if cmd.Name != tt.name || !slices.Equal(cmd.Args, tt.args) {
+			t.Errorf("parseCommand(%q, %q) = %q %q, want %q %q", tt.text, tt.username, cmd.Name, cmd.Args, tt.name, tt.args)
+		}
+	}
+}
+
+func TestRouterSetUsername(t *testing.T) {
+	r := NewRouter()
+	r.Handle("price", func(context.Context, Command) (string, error) { return "ok", nil })
+	msg := &Message{Text: "/price@OtherBot"}
+
+	if _, ok := r.Dispatch(context.Background(), msg); !ok {
+		t.Error("command for another bot ignored before the username is known")
+	}
+	r.SetUsername("GiaVangBot")
+	if _, ok := r.Dispatch(context.Background(), msg); ok {
+		t.Error("command for another bot answered once the username is known")
+	}
+}
+
+func TestRouterNotFound(t *testing.T) {
+	r := NewRouter()
+	r.Handle("price", func(context.Context, Command) (string, error) { return "ok", nil })
+	r.Handle("help", func(context.Context, Command) (string, error) { return "help", nil })
+	msg := &Message{Text: "/gia"}
+
+	if reply, ok := r.Dispatch(context.Background(), msg); !ok || reply != "" {
+		t.Errorf("unknown command without a fallback = %q, %t; want no reply", reply, ok)
+	}
+	r.NotFound(func(_ context.Context, cmd Command) (string, error) { return "no /" + cmd.Name, nil })
+	if reply, _ := r.Dispatch(context.Background(), msg); reply != "no /gia" {
+		t.Errorf("unknown command = %q, want the fallback's reply", reply)
+	}
+	if got := r.Commands(); !slices.Equal(got, []string{"price", "help"}) {
+		t.Errorf("Commands() = %q, want registration order", got)
+	}
+}
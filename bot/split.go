@@ -0,0 +1,141 @@
+package bottelegram
+
+import (
+	"html"
+	"regexp"
+	"slices"
+	"strings"
+	"unicode/utf8"
+)
+
+const (
+	// MaxMessageLength is the longest text Telegram accepts in one message.
+	MaxMessageLength = 4096
+	// MaxCaptionLength is the longest photo caption.
+	MaxCaptionLength = 1024
+)
+
+// EscapeHTML escapes text for parse_mode HTML. Names and other values put
+// into a message must go through it, as a stray "<" or "&" makes Telegram
+// reject the whole message.
+func EscapeHTML(s string) string {
+	return html.EscapeString(s)
+}
+
+var (
+	tagPattern = regexp.MustCompile(`</?(b|strong|i|em|u|s|code|pre|blockquote)>`)
+	// markupPattern matches any tag, to tell text from wrappers.
+	markupPattern = regexp.MustCompile(`<[^<>]*>`)
+	// unitPattern splits text into pieces a message may be cut between: a
+	// whole tag, a whole entity such as &amp;, or a single character.
+	unitPattern = regexp.MustCompile(`(?s)<[^<>]*>|&#?[0-9A-Za-z]+;|.`)
+)
+
+// SplitMessage cuts an HTML message into parts of at most limit characters,
+// preferring line breaks. Tags still open at a cut, such as the <pre> of a
+// price table, are closed at the end of the part and reopened in the next.
+// A line longer than a part is cut between characters, never inside a tag
+// or an entity, and parts that would hold nothing but tags are dropped.
+func SplitMessage(text string, limit int) []string {
+	if utf8.RuneCountInString(text) <= limit {
+		return []string{text}
+	}
+
+	var parts []string
+	var cur strings.Builder
+	var open []string // tag names open at the end of cur
+	curLen := 0
+
+	// fits reports whether n more characters leaving the tags in after open
+	// still fit, counting the tags that close the part.
+	fits := func(n int, after []string) bool {
+		return curLen+n+len(closingTags(after)) <= limit
+	}
+	add := func(s string, n int, after []string) {
+		cur.WriteString(s)
+		curLen += n
+		open = after
+	}
+	flush := func() {
+		if s := strings.TrimRight(cur.String(), "\n"); hasText(s) {
+			parts = append(parts, s+closingTags(open))
+		}
+		cur.Reset()
+		cur.WriteString(openingTags(open))
+		curLen = utf8.RuneCountInString(cur.String())
+	}
+
+	for _, line := range strings.SplitAfter(text, "\n") {
+		n := utf8.RuneCountInString(line)
+		after := trackTags(slices.Clone(open), line)
+		if !fits(n, after) {
+			flush()
+		}
+		if fits(n, after) {
+			add(line, n, after)
+			continue
+		}
+
+		// A single line longer than a whole part is cut hard.
+		for _, unit := range unitPattern.FindAllString(line, -1) {
+			n := utf8.RuneCountInString(unit)
+			after := trackTags(slices.Clone(open), unit)
+			if !fits(n, after) && hasText(cur.String()) {
+				flush()
+			}
+			add(unit, n, after)
+		}
+	}
+	if s := strings.TrimRight(cur.String(), "\n"); hasText(s) {
+		parts = append(parts, s)
+	}
+	return parts
+}
+
+func closingTags(open []string) string {
+	var sb strings.Builder
+	for i := len(open) - 1; i >= 0; i-- {
+		sb.WriteString("</" + open[i] + ">")
+	}
+	return sb.String()
+}
+
+func openingTags(open []string) string {
+	var sb strings.Builder
+	for _, tag := range open {
+		sb.WriteString("<" + tag + ">")
+	}
+	return sb.String()
+}
+
+// hasText reports whether s has anything besides tags and white space.
+func hasText(s string) bool {
+	return strings.TrimSpace(markupPattern.ReplaceAllString(s, "")) != ""
+}
+
+// trackTags updates the stack of open tags with the tags found in line.
+func trackTags(open []string, line string) []string {
+	for _, m := range tagPattern.FindAllStringSubmatch(line, -1) {
+		name := m[1]
+		if strings.HasPrefix(m[0], "</") {
+			for i := len(open) - 1; i >= 0; i-- {
+				if open[i] == name {
+					open = append(open[:i], open[i+1:]...)
+					break
+				}
+			}
+		} else {
+			open = append(open, name)
+		}
+	}
+	return open
+}
+
+// TruncateMessage returns the first part SplitMessage would cut text into,
+// for fields such as photo captions that cannot be sent in several parts.
+func TruncateMessage(text string, limit int) string {
+	if parts := SplitMessage(text, limit); len(parts) > 0 {
+		return parts[0]
+	}
+	return ""
+}
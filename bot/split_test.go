@@ -0,0 +1,119 @@
+package bottelegram
+
+import (
+	"regexp"
+	"strings"
+	"testing"
+	"unicode/utf8"
+)
+
+var entityPattern = regexp.MustCompile(`&#?[0-9A-Za-z]+;`)
+
+func TestSplitMessage(t *testing.T) {
+	table := "<b>Bảng giá</b>\n<pre>\n" + strings.Repeat("SJC        120.5  122.5\n", 10) + "</pre>\n⏰ 08:00"
+
+	tests := []struct {
+		name  string
+		text  string
+		limit int
+		parts int // 0 means more than one, any number
+	}{
+		{"short", "<b>SJC</b> 120.5", 100, 1},
+		{"table across lines", table, 80, 0},
+		{"long line in pre", "<pre>" + strings.Repeat("1234567890", 12) + "</pre>", 40, 0},
+		{"long line in nested tags", "<b><i>" + strings.Repeat("giá vàng ", 20) + "</i></b>", 30, 0},
+		{"entities at the cut", strings.Repeat("a&amp;b&lt;", 20), 17, 0},
+		{"wrapper before long line", "<pre>\n" + strings.Repeat("x", 50) + "\n</pre>", 20, 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			parts := SplitMessage(tt.text, tt.limit)
+			switch {
+			case tt.parts != 0 && len(parts) != tt.parts:
+				t.Fatalf("got %d parts, want %d: %q", len(parts), tt.parts, parts)
+			case tt.parts == 0 && len(parts) < 2:
+				t.Fatalf("got %d parts, want several: %q", len(parts), parts)
+			}
+
+			var joined strings.Builder
+			for _, part := range parts {
+				if n := utf8.RuneCountInString(part); n > tt.limit {
+					t.Errorf("part has %d characters, limit %d: %q", n, tt.limit, part)
+				}
+				if !hasText(part) {
+					t.Errorf("part holds only tags: %q", part)
+				}
+				checkBalanced(t, part)
+				checkWhole(t, part)
+				joined.WriteString(part)
+			}
+			if got, want := plainText(joined.String()), plainText(tt.text); got != want {
+				t.Errorf("text changed:\n got %q\nwant %q", got, want)
+			}
+		})
+	}
+}
+
+func TestTruncateMessage(t *testing.T) {
+	tests := []struct {
+		name  string
+		text  string
+		limit int
+		want  string
+	}{
+		{"short", "<b>SJC</b> 120.5", 100, "<b>SJC</b> 120.5"},
+		{"cut at a line break", "<b>Giá vàng 30 ngày</b>\nsjc, doji_hn", 25, "<b>Giá vàng 30 ngày</b>"},
+		{"tag closed at the cut", "<i>" + strings.Repeat("sjc, ", 10) + "</i>", 20, "<i>sjc, sjc, sjc</i>"},
+		{"entity kept whole", "DOJI &lt;HN&gt; " + strings.Repeat("x", 20), 12, "DOJI &lt;HN"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := TruncateMessage(tt.text, tt.limit)
+			if got != tt.want {
+				t.Errorf("TruncateMessage = %q, want %q", got, tt.want)
+			}
+			if n := utf8.RuneCountInString(got); n > tt.limit {
+				t.Errorf("got %d characters, limit %d", n, tt.limit)
+			}
+			checkBalanced(t, got)
+			checkWhole(t, got)
+		})
+	}
+}
+
+// checkBalanced fails unless every tag in part is closed in order.
+func checkBalanced(t *testing.T, part string) {
+	t.Helper()
+	var open []string
+	for _, m := range tagPattern.FindAllStringSubmatch(part, -1) {
+		if !strings.HasPrefix(m[0], "</") {
+			open = append(open, m[1])
+			continue
+		}
+		if len(open) == 0 || open[len(open)-1] != m[1] {
+			t.Errorf("unexpected %s in %q", m[0], part)
+			return
+		}
+		open = open[:len(open)-1]
+	}
+	if len(open) > 0 {
+		t.Errorf("tags %v left open in %q", open, part)
+	}
+}
+
+// checkWhole fails if part starts or ends inside a tag or an entity.
+func checkWhole(t *testing.T, part string) {
+	t.Helper()
+	rest := markupPattern.ReplaceAllString(part, "")
+	if strings.ContainsAny(rest, "<>") {
+		t.Errorf("tag cut in %q", part)
+	}
+	if strings.Contains(entityPattern.ReplaceAllString(rest, ""), "&") {
+		t.Errorf("entity cut in %q", part)
+	}
+}
+
+// plainText is what Telegram shows, ignoring the line breaks dropped at cuts.
+func plainText(s string) string {
+	return strings.ReplaceAll(markupPattern.ReplaceAllString(s, ""), "\n", "")
+}
@@ -0,0 +1,93 @@
+package bottelegram
+
+import (
+	"context"
+	"log"
+	"strconv"
+	"time"
+)
+
+// Update is an incoming Telegram update. Only messages are handled.
+type Update struct {
+	UpdateID int      `json:"update_id"`
+	Message  *Message `json:"message,omitempty"`
+}
+
+type Message struct {
+	MessageID int    `json:"message_id"`
+	From      *User  `json:"from,omitempty"`
+	Chat      Chat   `json:"chat"`
+	Text      string `json:"text"`
+}
+
+type User struct {
+	ID       int64  `json:"id"`
+	Username string `json:"username"`
+}
+
+type Chat struct {
+	ID    int64  `json:"id"`
+	Type  string `json:"type"`
+	Title string `json:"title"`
+}
+
+// ChatID returns the chat ID in the string form used for sending.
+func (m *Message) ChatID() string {
+	return strconv.FormatInt(m.Chat.ID, 10)
+}
+
+const pollTimeout = 30 * time.Second // how long Telegram may hold a getUpdates call
+
+// GetMe returns the bot's own user.
+func (b *Bot) GetMe(ctx context.Context) (*User, error) {
+	var me User
+	if err := b.client.Call(ctx, "getMe", map[string]any{}, &me); err != nil {
+		return nil, err
+	}
+	return &me, nil
+}
+
+// GetUpdates long-polls for updates after offset.
+func (b *Bot) GetUpdates(ctx context.Context, offset int) ([]Update, error) {
+	return b.client.GetUpdates(ctx, offset, pollTimeout)
+}
+
+// Poll receives updates until ctx is done and answers commands through
+// router.
+func (b *Bot) Poll(ctx context.Context, router *Router) {
+	offset := 0
+	for ctx.Err() == nil {
+		updates, err := b.GetUpdates(ctx, offset)
+		if err != nil {
+			if ctx.Err() != nil {
+				return
+			}
+			log.Printf("Telegram getUpdates failed: %v", err)
+			select {
+			case <-ctx.Done():
+				return
+			case <-time.After(5 * time.Second):
+			}
+			continue
+		}
+
+		for _, update := range updates {
+			offset = update.UpdateID + 1
+			b.HandleUpdate(ctx, router, update)
+		}
+	}
+}
+
+// HandleUpdate dispatches a command message and sends the reply.
+func (b *Bot) HandleUpdate(ctx context.Context, router *Router, update Update) {
+	if update.Message == nil {
+		return
+	}
+	reply, ok := router.Dispatch(ctx, update.Message)
+	if !ok || reply == "" {
+		return
+	}
+	if err := b.SendMessage(update.Message.ChatID(), reply); err != nil {
+		log.Printf("Cannot reply to chat %s: %v", update.Message.ChatID(), err)
+	}
+}
@@ -0,0 +1,73 @@
+package bottelegram
+
+import (
+	"context"
+	"crypto/subtle"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"regexp"
+	"time"
+)
+
+// SecretTokenHeader carries the secret given to setWebhook on every update
+// Telegram delivers.
+const SecretTokenHeader = "X-Telegram-Bot-Api-Secret-Token"
+
+// secretTokenPattern is what Telegram accepts as a webhook secret.
+var secretTokenPattern = regexp.MustCompile(`^[A-Za-z0-9_-]{1,256}$`)
+
+// ValidateSecretToken reports whether secret can be used with setWebhook.
+func ValidateSecretToken(secret string) error {
+	if !secretTokenPattern.MatchString(secret) {
+		return fmt.Errorf("webhook secret must be 1-256 characters of A-Z, a-z, 0-9, _ and -")
+	}
+	return nil
+}
+
+// SetWebhook asks Telegram to deliver updates to webhookURL with secret in
+// the SecretTokenHeader. Pending updates from polling are kept.
+func (b *Bot) SetWebhook(ctx context.Context, webhookURL, secret string) error {
+	if err := ValidateSecretToken(secret); err != nil {
+		return err
+	}
+	params := map[string]any{
+		"url":             webhookURL,
+		"secret_token":    secret,
+		"allowed_updates": []string{"message"},
+	}
+	return b.client.Call(ctx, "setWebhook", params, nil)
+}
+
+// DeleteWebhook switches the bot back to getUpdates.
+func (b *Bot) DeleteWebhook(ctx context.Context) error {
+	return b.client.Call(ctx, "deleteWebhook", map[string]any{}, nil)
+}
+
+// WebhookHandler receives updates pushed by Telegram and answers them through
+// router. Requests without the matching secret get 401. The update is
+// acknowledged before the reply is sent so a slow command does not make
+// Telegram redeliver it.
+func (b *Bot) WebhookHandler(router *Router, secret string) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		got := r.Header.Get(SecretTokenHeader)
+		if subtle.ConstantTimeCompare([]byte(got), []byte(secret)) != 1 {
+			http.Error(w, "invalid secret token", http.StatusUnauthorized)
+			return
+		}
+
+		var update Update
+		if err := json.NewDecoder(io.LimitReader(r.Body, 1<<20)).Decode(&update); err != nil {
+			http.Error(w, "invalid update", http.StatusBadRequest)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+
+		go func() {
+			ctx, cancel := context.WithTimeout(context.WithoutCancel(r.Context()), 30*time.Second)
+			defer cancel()
+			b.HandleUpdate(ctx, router, update)
+		}()
+	})
+}
@@ -0,0 +1,553 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/robfig/cron/v3"
+
+	bottelegram "pricegoldtoday/bot"
+	"pricegoldtoday/chart"
+	"pricegoldtoday/vntime"
+)
+
+const maxHistoryDays = 90
+
+// botCommands answers Telegram commands from the same store as the HTTP API.
+type botCommands struct {
+	cfg    TelegramConfig // defaults for new subscribers
+	bot    *bottelegram.Bot
+	alerts *alertEvaluator
+}
+
+// commandHelp is the /help entry of a command: its arguments and what it
+// does.
+type commandHelp struct {
+	Usage string
+	Help  string
+}
+
+// commandLabels are the strings of the Telegram command replies in one
+// language. Those shared with other chat messages are in chatLabels.
+type commandLabels struct {
+	Commands  map[string]commandHelp // by command name
+	HelpTitle string
+	Unknown   string
+	Usage     string // takes the command with its arguments
+
+	Subscribed   string // takes the gold types and the schedule
+	AllTypes     string
+	Unsubscribed string
+	AlertCreated string // takes the rule ID and the rule
+	AlertsTitle  string
+	NoAlerts     string
+	AlertDeleted string
+
+	BadGoldType       string // takes the gold type and the valid ones
+	BadDays           string // takes the lowest and highest number of days
+	BadSchedule       string // takes the cron spec
+	NoPrices          string
+	NoData            string
+	HistoryFailed     string
+	ChartFailed       string
+	SendChartFailed   string
+	SubscribersFailed string
+	SaveFailed        string
+	NotSubscribed     string
+	UnsubscribeFailed string
+	AlertSaveFailed   string
+	AlertsFailed      string
+	AlertDeleteFailed string
+	NoAlert           string // takes the rule ID
+}
+
+var commandLabelsByLocale = map[string]commandLabels{
+	"vi": {
+		Commands: map[string]commandHelp{
+			"price":       {"[loại]", "giá mới nhất của một loại vàng"},
+			"all":         {"", "bảng giá tất cả các loại vàng"},
+			"history":     {"<loại> <số ngày>", "lịch sử giá theo ngày"},
+			"chart":       {"[loại...] [số ngày]", "biểu đồ giá mua/bán"},
+			"subscribe":   {"[loại...]", "nhận bảng giá định kỳ"},
+			"unsubscribe": {"", "ngừng nhận bảng giá"},
+			"lang":        {"<vi|en>", "ngôn ngữ bảng giá"},
+			"unit":        {"<luong|chi>", "đơn vị: triệu/lượng hoặc nghìn/chỉ"},
+			"schedule":    {"<cron>", "lịch gửi bảng giá, ví dụ 0 8 * * *"},
+			"alert":       {"<loại> buy|sell >|< <giá> | move <%> | spread > <giá>", "tạo cảnh báo giá"},
+			"alerts":      {"", "danh sách cảnh báo"},
+			"unalert":     {"<mã>", "xoá cảnh báo"},
+			"help":        {"", "danh sách lệnh"},
+		},
+		HelpTitle: "Các lệnh hỗ trợ",
+		Unknown:   "Lệnh không hợp lệ. Gõ /help để xem danh sách lệnh.",
+		Usage:     "cú pháp: %s",
+
+		Subscribed:   "✅ Đã đăng ký nhận bảng giá (%s), lịch gửi: <code>%s</code>",
+		AllTypes:     "tất cả các loại vàng",
+		Unsubscribed: "Đã huỷ đăng ký nhận bảng giá.",
+		AlertCreated: "✅ Đã tạo cảnh báo <code>%s</code>: %s",
+		AlertsTitle:  "Cảnh báo giá",
+		NoAlerts:     "Chưa có cảnh báo nào. Gõ /help để xem cách tạo.",
+		AlertDeleted: "Đã xoá cảnh báo.",
+
+		BadGoldType:       "loại vàng %q không hợp lệ, chọn một trong: %s",
+		BadDays:           "số ngày phải từ %d đến %d",
+		BadSchedule:       "lịch gửi %q không hợp lệ",
+		NoPrices:          "chưa lấy được giá, vui lòng thử lại sau",
+		NoData:            "chưa có dữ liệu giá",
+		HistoryFailed:     "không đọc được lịch sử giá",
+		ChartFailed:       "không vẽ được biểu đồ",
+		SendChartFailed:   "không gửi được biểu đồ",
+		SubscribersFailed: "không đọc được danh sách đăng ký",
+		SaveFailed:        "không lưu được đăng ký",
+		NotSubscribed:     "chat chưa đăng ký, gõ /subscribe trước",
+		UnsubscribeFailed: "không huỷ được đăng ký",
+		AlertSaveFailed:   "không lưu được cảnh báo",
+		AlertsFailed:      "không đọc được danh sách cảnh báo",
+		AlertDeleteFailed: "không xoá được cảnh báo",
+		NoAlert:           "không có cảnh báo %q",
+	},
+	"en": {
+		Commands: map[string]commandHelp{
+			"price":       {"[type]", "latest price of one gold type"},
+			"all":         {"", "price table of every gold type"},
+			"history":     {"<type> <days>", "daily price history"},
+			"chart":       {"[types...] [days]", "buy/sell price chart"},
+			"subscribe":   {"[types...]", "receive the price table on a schedule"},
+			"unsubscribe": {"", "stop receiving the price table"},
+			"lang":        {"<vi|en>", "price table language"},
+			"unit":        {"<luong|chi>", "unit: million/tael or thousand/chi"},
+			"schedule":    {"<cron>", "price table schedule, e.g. 0 8 * * *"},
+			"alert":       {"<type> buy|sell >|< <price> | move <%> | spread > <price>", "create a price alert"},
+			"alerts":      {"", "list your alerts"},
+			"unalert":     {"<id>", "delete an alert"},
+			"help":        {"", "list commands"},
+		},
+		HelpTitle: "Commands",
+		Unknown:   "Unknown command. Send /help for the list of commands.",
+		Usage:     "usage: %s",
+
+		Subscribed:   "✅ Subscribed to the price table (%s), schedule: <code>%s</code>",
+		AllTypes:     "all gold types",
+		Unsubscribed: "Unsubscribed from the price table.",
+		AlertCreated: "✅ Alert <code>%s</code> created: %s",
+		AlertsTitle:  "Price alerts",
+		NoAlerts:     "No alerts yet. Send /help to see how to create one.",
+		AlertDeleted: "Alert deleted.",
+
+		BadGoldType:       "unknown gold type %q, choose one of: %s",
+		BadDays:           "the number of days must be between %d and %d",
+		BadSchedule:       "invalid schedule %q",
+		NoPrices:          "prices are not available yet, please try again later",
+		NoData:            "no price data yet",
+		HistoryFailed:     "cannot read the price history",
+		ChartFailed:       "cannot draw the chart",
+		SendChartFailed:   "cannot send the chart",
+		SubscribersFailed: "cannot read the subscriptions",
+		SaveFailed:        "cannot save the subscription",
+		NotSubscribed:     "this chat is not subscribed, send /subscribe first",
+		UnsubscribeFailed: "cannot cancel the subscription",
+		AlertSaveFailed:   "cannot save the alert",
+		AlertsFailed:      "cannot read the alerts",
+		AlertDeleteFailed: "cannot delete the alert",
+		NoAlert:           "no alert %q",
+	},
+}
+
+func commandLabelsFor(locale string) commandLabels {
+	if l, ok := commandLabelsByLocale[locale]; ok {
+		return l
+	}
+	return commandLabelsByLocale[bottelegram.DefaultLocale]
+}
+
+// usageError tells how to call a command, with its arguments as /help lists
+// them.
+func (l commandLabels) usageError(name string) error {
+	return fmt.Errorf(l.Usage, "/"+name+" "+l.Commands[name].Usage)
+}
+
+func newCommandRouter(cfg TelegramConfig, bot *bottelegram.Bot, alerts *alertEvaluator) *bottelegram.Router {
+	c := &botCommands{cfg: cfg, bot: bot, alerts: alerts}
+
+	r := bottelegram.NewRouter()
+	r.Handle("price", c.price)
+	r.Handle("all", c.all)
+	r.Handle("history", c.history)
+	r.Handle("chart", c.chart)
+	r.Handle("subscribe", c.subscribe)
+	r.Handle("unsubscribe", c.unsubscribe)
+	r.Handle("lang", c.lang)
+	r.Handle("unit", c.unit)
+	r.Handle("schedule", c.schedule)
+	r.Handle("alert", c.alert)
+	r.Handle("alerts", c.listAlerts)
+	r.Handle("unalert", c.unalert)
+	r.Handle("help", func(ctx context.Context, cmd bottelegram.Command) (string, error) {
+		return c.help(ctx, cmd, r.Commands())
+	})
+	r.NotFound(func(ctx context.Context, cmd bottelegram.Command) (string, error) {
+		return c.labels(ctx, cmd).Unknown, nil
+	})
+	return r
+}
+
+// resolveBotUsername asks Telegram for the bot's username and hands it to r,
+// so commands addressed to other bots in a group are ignored. It gives up
+// after a few seconds rather than hold up the commands.
+func resolveBotUsername(ctx context.Context, bot *bottelegram.Bot, r *bottelegram.Router) {
+	ctx, cancel := context.WithTimeout(ctx, 10*time.Second)
+	defer cancel()
+
+	me, err := bot.GetMe(ctx)
+	if err != nil {
+		log.Printf("Cannot get the bot's username, answering commands addressed to any bot: %v", err)
+		return
+	}
+	r.SetUsername(me.Username)
+}
+
+func (c *botCommands) price(ctx context.Context, cmd bottelegram.Command) (string, error) {
+	goldType := defaultGoldType
+	if len(cmd.Args) > 0 {
+		goldType = cmd.Args[0]
+	}
+	opts := c.chatOptions(ctx, cmd.Message.ChatID())
+	provider, err := lookupGoldType(goldType, opts.Locale)
+	if err != nil {
+		return "", err
+	}
+
+	l := commandLabelsFor(opts.Locale)
+	goldPrice, err := loadGoldPrice(provider.ID)
+	if err != nil {
+		return "", errors.New(l.NoPrices)
+	}
+	points := historyFromGoldPrice(goldPrice)
+	if len(points) == 0 {
+		return "", errors.New(l.NoData)
+	}
+
+	latest := points[len(points)-1]
+	var prev PricePoint
+	if len(points) > 1 {
+		prev = points[len(points)-2]
+	}
+
+	labels := labelsFor(opts.Locale)
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "<b>%s</b> (%s)\n", bottelegram.EscapeHTML(provider.Name), formatDay(latest.Date, labels.DayFmt))
+	fmt.Fprintf(&sb, "%s: %s %s\n", labels.Buy, bottelegram.FormatPrice(latest.Buy, opts.Unit), bottelegram.FormatChange(latest.Buy, prev.Buy, opts.Unit))
+	fmt.Fprintf(&sb, "%s: %s %s\n", labels.Sell, bottelegram.FormatPrice(latest.Sell, opts.Unit), bottelegram.FormatChange(latest.Sell, prev.Sell, opts.Unit))
+	fmt.Fprintf(&sb, "<i>"+labels.Unit+"</i>\n", bottelegram.UnitLabel(opts.Unit, opts.Locale))
+	fmt.Fprintf(&sb, "⏰ "+labels.Updated, goldPrice.UpdatedAt.In(vntime.Location).Format(labels.TimeFmt))
+	return sb.String(), nil
+}
+
+func (c *botCommands) all(ctx context.Context, cmd bottelegram.Command) (string, error) {
+	opts := c.chatOptions(ctx, cmd.Message.ChatID())
+	goldPrices := loadGoldPrices(ctx, GOLDTYPES)
+	if len(goldPrices) == 0 {
+		return "", errors.New(commandLabelsFor(opts.Locale).NoPrices)
+	}
+	// Use the chat's language and unit, but every gold type.
+	return c.bot.FormatDigest(goldPriceResponseFor(&Subscriber{}, goldPrices), opts)
+}
+
+// chatOptions returns the language and unit of a subscribed chat, or the
+// configured defaults for other chats.
+func (c *botCommands) chatOptions(ctx context.Context, chatID string) bottelegram.DigestOptions {
+	return chatOptions(ctx, chatID, c.cfg)
+}
+
+// labels returns the command strings in the language of the chat cmd came
+// from.
+func (c *botCommands) labels(ctx context.Context, cmd bottelegram.Command) commandLabels {
+	return commandLabelsFor(c.chatOptions(ctx, cmd.Message.ChatID()).Locale)
+}
+
+func (c *botCommands) help(ctx context.Context, cmd bottelegram.Command, commands []string) (string, error) {
+	l := c.labels(ctx, cmd)
+	var sb strings.Builder
+	sb.WriteString("<b>" + l.HelpTitle + "</b>\n")
+	for _, name := range commands {
+		help := l.Commands[name]
+		sb.WriteString("/" + name)
+		if help.Usage != "" {
+			sb.WriteString(" " + bottelegram.EscapeHTML(help.Usage))
+		}
+		sb.WriteString(" - " + help.Help + "\n")
+	}
+	return sb.String(), nil
+}
+
+func (c *botCommands) history(ctx context.Context, cmd bottelegram.Command) (string, error) {
+	opts := c.chatOptions(ctx, cmd.Message.ChatID())
+	l := commandLabelsFor(opts.Locale)
+	if len(cmd.Args) < 1 {
+		return "", l.usageError("history")
+	}
+	provider, err := lookupGoldType(cmd.Args[0], opts.Locale)
+	if err != nil {
+		return "", err
+	}
+	days := 7
+	if len(cmd.Args) > 1 {
+		days, err = strconv.Atoi(cmd.Args[1])
+		if err != nil || days < 1 || days > maxHistoryDays {
+			return "", fmt.Errorf(l.BadDays, 1, maxHistoryDays)
+		}
+	}
+
+	from := vntime.Today().AddDate(0, 0, -(days - 1))
+	points, err := store.History(ctx, provider.ID, from, vntime.Today())
+	if err != nil {
+		return "", errors.New(l.HistoryFailed)
+	}
+	labels := labelsFor(opts.Locale)
+	if len(points) == 0 {
+		return fmt.Sprintf("<b>%s</b>: "+labels.NoHistory, bottelegram.EscapeHTML(provider.Name), days), nil
+	}
+
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "<b>"+labels.History+"</b>\n<pre>\n", bottelegram.EscapeHTML(provider.Name), days)
+	sb.WriteString(labels.HistoryHeader + "\n")
+	for _, p := range points {
+		fmt.Fprintf(&sb, "%s %8s %7s\n", formatDay(p.Date, labels.DayFmt), bottelegram.FormatPrice(p.Buy, opts.Unit), bottelegram.FormatPrice(p.Sell, opts.Unit))
+	}
+	sb.WriteString("</pre>\n")
+	fmt.Fprintf(&sb, "<i>"+labels.Unit+"</i>", bottelegram.UnitLabel(opts.Unit, opts.Locale))
+	return sb.String(), nil
+}
+
+func (c *botCommands) lang(ctx context.Context, cmd bottelegram.Command) (string, error) {
+	locales := c.bot.Locales()
+	slices.Sort(locales)
+	if len(cmd.Args) != 1 || !slices.Contains(locales, cmd.Args[0]) {
+		return "", fmt.Errorf(c.labels(ctx, cmd).Usage, "/lang <"+strings.Join(locales, "|")+">")
+	}
+	return c.updateSubscriber(ctx, cmd, func(sub *Subscriber) { sub.Language = cmd.Args[0] })
+}
+
+func (c *botCommands) unit(ctx context.Context, cmd bottelegram.Command) (string, error) {
+	if len(cmd.Args) != 1 || !bottelegram.ValidUnit(cmd.Args[0]) {
+		return "", c.labels(ctx, cmd).usageError("unit")
+	}
+	return c.updateSubscriber(ctx, cmd, func(sub *Subscriber) { sub.Unit = cmd.Args[0] })
+}
+
+// schedule sets the cron spec of the chat's digest, such as "0 8 * * *".
+func (c *botCommands) schedule(ctx context.Context, cmd bottelegram.Command) (string, error) {
+	l := c.labels(ctx, cmd)
+	if len(cmd.Args) == 0 {
+		return "", l.usageError("schedule")
+	}
+	spec := strings.Join(cmd.Args, " ")
+	if _, err := cron.ParseStandard(spec); err != nil {
+		return "", fmt.Errorf(l.BadSchedule, spec)
+	}
+	return c.updateSubscriber(ctx, cmd, func(sub *Subscriber) { sub.Schedule = spec })
+}
+
+// updateSubscriber changes the chat's preferences. The next digest is sent
+// even if prices did not change, so the chat sees the new format.
+func (c *botCommands) updateSubscriber(ctx context.Context, cmd bottelegram.Command, update func(*Subscriber)) (string, error) {
+	l := c.labels(ctx, cmd)
+	found, err := modifySubscriber(ctx, cmd.Message.ChatID(), func(sub *Subscriber) {
+		update(sub)
+		sub.LastFingerprint = ""
+	})
+	if err != nil {
+		return "", errors.New(l.SaveFailed)
+	}
+	if !found {
+		return "", errors.New(l.NotSubscribed)
+	}
+	return "✅ OK", nil
+}
+
+// chart sends the image itself and returns no text reply.
+func (c *botCommands) chart(ctx context.Context, cmd bottelegram.Command) (string, error) {
+	opts := c.chatOptions(ctx, cmd.Message.ChatID())
+	l := commandLabelsFor(opts.Locale)
+	args := cmd.Args
+	days := defaultChartDays
+	if len(args) > 0 {
+		if n, err := strconv.Atoi(args[len(args)-1]); err == nil {
+			if n < 2 || n > maxHistoryDays {
+				return "", fmt.Errorf(l.BadDays, 2, maxHistoryDays)
+			}
+			days = n
+			args = args[:len(args)-1]
+		}
+	}
+
+	goldTypes := []string{defaultGoldType}
+	if len(args) > 0 {
+		goldTypes = nil
+		for _, arg := range args {
+			provider, err := lookupGoldType(arg, opts.Locale)
+			if err != nil {
+				return "", err
+			}
+			goldTypes = append(goldTypes, provider.ID)
+		}
+	}
+
+	labels := labelsFor(opts.Locale)
+	img, err := renderPriceChart(ctx, goldTypes, recentDays(days), chatChartOptions(opts))
+	if errors.Is(err, chart.ErrNoData) {
+		return fmt.Sprintf(labels.NoHistory, days), nil
+	}
+	if err != nil {
+		return "", errors.New(l.ChartFailed)
+	}
+	caption := fmt.Sprintf(labels.Chart+": %s", days, bottelegram.EscapeHTML(strings.Join(goldTypes, ", ")))
+	if err := c.bot.SendPhoto(cmd.Message.ChatID(), img, caption); err != nil {
+		return "", errors.New(l.SendChartFailed)
+	}
+	return "", nil
+}
+
+func (c *botCommands) subscribe(ctx context.Context, cmd bottelegram.Command) (string, error) {
+	opts := c.chatOptions(ctx, cmd.Message.ChatID())
+	l := commandLabelsFor(opts.Locale)
+	var goldTypes []string
+	for _, arg := range cmd.Args {
+		provider, err := lookupGoldType(arg, opts.Locale)
+		if err != nil {
+			return "", err
+		}
+		if !slices.Contains(goldTypes, provider.ID) {
+			goldTypes = append(goldTypes, provider.ID)
+		}
+	}
+
+	subscribersMu.Lock()
+	defer subscribersMu.Unlock()
+
+	chatID := cmd.Message.ChatID()
+	sub, err := findSubscriber(ctx, chatID)
+	if err != nil {
+		return "", errors.New(l.SubscribersFailed)
+	}
+	if sub == nil {
+		sub = newSubscriber(chatID, c.cfg)
+	}
+	sub.Providers = goldTypes
+	if err := store.SaveSubscriber(ctx, sub); err != nil {
+		return "", errors.New(l.SaveFailed)
+	}
+
+	selection := l.AllTypes
+	if len(sub.Providers) > 0 {
+		selection = bottelegram.EscapeHTML(strings.Join(sub.Providers, ", "))
+	}
+	return fmt.Sprintf(l.Subscribed, selection, sub.Schedule), nil
+}
+
+func (c *botCommands) unsubscribe(ctx context.Context, cmd bottelegram.Command) (string, error) {
+	l := c.labels(ctx, cmd)
+	if err := removeSubscriber(ctx, cmd.Message.ChatID()); err != nil {
+		return "", errors.New(l.UnsubscribeFailed)
+	}
+	return l.Unsubscribed, nil
+}
+
+func (c *botCommands) alert(ctx context.Context, cmd bottelegram.Command) (string, error) {
+	opts := c.chatOptions(ctx, cmd.Message.ChatID())
+	l := commandLabelsFor(opts.Locale)
+	rule, err := parseAlertRule(cmd.Args, opts.Locale)
+	if err != nil {
+		return "", err
+	}
+	rule.ID = newAlertID()
+	rule.ChatID = cmd.Message.ChatID()
+	rule.CreatedAt = time.Now()
+	if err := store.SaveAlert(ctx, rule); err != nil {
+		return "", errors.New(l.AlertSaveFailed)
+	}
+	return fmt.Sprintf(l.AlertCreated, rule.ID, bottelegram.EscapeHTML(rule.Describe(alertTextFor(opts)))), nil
+}
+
+func (c *botCommands) listAlerts(ctx context.Context, cmd bottelegram.Command) (string, error) {
+	opts := c.chatOptions(ctx, cmd.Message.ChatID())
+	l := commandLabelsFor(opts.Locale)
+	rules, err := store.ListAlerts(ctx)
+	if err != nil {
+		return "", errors.New(l.AlertsFailed)
+	}
+
+	var sb strings.Builder
+	for _, rule := range rules {
+		if rule.ChatID == cmd.Message.ChatID() {
+			fmt.Fprintf(&sb, "<code>%s</code> %s\n", rule.ID, bottelegram.EscapeHTML(rule.Describe(alertTextFor(opts))))
+		}
+	}
+	if sb.Len() == 0 {
+		return l.NoAlerts, nil
+	}
+	return "<b>" + l.AlertsTitle + "</b>\n" + sb.String(), nil
+}
+
+func (c *botCommands) unalert(ctx context.Context, cmd bottelegram.Command) (string, error) {
+	l := c.labels(ctx, cmd)
+	if len(cmd.Args) != 1 {
+		return "", l.usageError("unalert")
+	}
+	ok, err := c.alerts.Delete(ctx, cmd.Message.ChatID(), cmd.Args[0])
+	if err != nil {
+		return "", errors.New(l.AlertDeleteFailed)
+	}
+	if !ok {
+		return "", fmt.Errorf(l.NoAlert, cmd.Args[0])
+	}
+	return l.AlertDeleted, nil
+}
+
+// lookupGoldType resolves a gold type among the enabled ones. The error is
+// written in locale.
+func lookupGoldType(goldType, locale string) (Provider, error) {
+	provider, ok := enabledProvider(strings.ToLower(goldType))
+	if !ok {
+		return Provider{}, fmt.Errorf(commandLabelsFor(locale).BadGoldType, goldType, strings.Join(GOLDTYPES, ", "))
+	}
+	return provider, nil
+}
+
+// chatOptions returns the language and unit of a subscribed chat, or the
+// defaults from cfg for other chats.
+func chatOptions(ctx context.Context, chatID string, cfg TelegramConfig) bottelegram.DigestOptions {
+	if sub, err := findSubscriber(ctx, chatID); err == nil && sub != nil {
+		return sub.DigestOptions()
+	}
+	return bottelegram.DigestOptions{Locale: cfg.Language, Unit: cfg.Unit}
+}
+
+func findSubscriber(ctx context.Context, chatID string) (*Subscriber, error) {
+	subscribers, err := store.ListSubscribers(ctx)
+	if err != nil {
+		return nil, err
+	}
+	for _, sub := range subscribers {
+		if sub.ChatID == chatID {
+			return sub, nil
+		}
+	}
+	return nil, nil
+}
+
+// formatDay formats a stored YYYY-MM-DD date with layout.
+func formatDay(date, layout string) string {
+	t, err := time.Parse(historyDateLayout, date)
+	if err != nil {
+		return date
+	}
+	return t.Format(layout)
+}
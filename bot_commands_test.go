@@ -0,0 +1,66 @@
+package main
+
+import (
+	"context"
+	"slices"
+	"strings"
+	"testing"
+
+	bottelegram "pricegoldtoday/bot"
+)
+
+func TestCommandLabels(t *testing.T) {
+	commands := newCommandRouter(TelegramConfig{}, nil, nil).Commands()
+	for locale, l := range commandLabelsByLocale {
+		for _, name := range commands {
+			if l.Commands[name].Help == "" {
+				t.Errorf("%s: /%s has no help", locale, name)
+			}
+		}
+		for name := range l.Commands {
+			if !slices.Contains(commands, name) {
+				t.Errorf("%s: help for /%s, which is not a command", locale, name)
+			}
+		}
+	}
+}
+
+func TestScheduleCommand(t *testing.T) {
+	useTestBackends(t)
+	ctx := context.Background()
+	cfg := TelegramConfig{Language: "en", Schedule: "0 8 * * *"}
+	if err := store.SaveSubscriber(ctx, newSubscriber("42", cfg)); err != nil {
+		t.Fatal(err)
+	}
+	c := &botCommands{cfg: cfg}
+
+	tests := []struct {
+		name     string
+		chatID   int64
+		text     string
+		wantErr  string
+		schedule string
+	}{
+		{"cron spec", 42, "30 7 * * 1-5", "", "30 7 * * 1-5"},
+		{"descriptor", 42, "@every 2h", "", "@every 2h"},
+		{"invalid", 42, "0 25 * * *", `invalid schedule "0 25 * * *"`, "@every 2h"},
+		{"missing", 42, "", "usage: /schedule <cron>", "@every 2h"},
+		{"not subscribed", 7, "0 9 * * *", "send /subscribe first", "@every 2h"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cmd := bottelegram.Command{Name: "schedule", Args: strings.Fields(tt.text), Message: &bottelegram.Message{Chat: bottelegram.Chat{ID: tt.chatID}}}
+			_, err := c.schedule(ctx, cmd)
+			if tt.wantErr == "" && err != nil || tt.wantErr != "" && (err == nil || !strings.Contains(err.Error(), tt.wantErr)) {
+				t.Errorf("/schedule %s = %v, want error %q", tt.text, err, tt.wantErr)
+			}
+			sub, err := findSubscriber(ctx, "42")
+			if err != nil {
+				t.Fatal(err)
+			}
+			if sub.Schedule != tt.schedule {
+				t.Errorf("schedule = %q, want %q", sub.Schedule, tt.schedule)
+			}
+		})
+	}
+}
@@ -0,0 +1,114 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestCircuitBreaker(t *testing.T) {
+	const cooldown = 20 * time.Millisecond
+	b := NewCircuitBreaker(2, cooldown)
+
+	steps := []struct {
+		name       string
+		do         func() bool
+		want       bool
+		wantState  breakerState
+		waitBefore time.Duration
+	}{
+		{name: "closed allows", do: b.Allow, want: true, wantState: breakerClosed},
+		{name: "first failure", do: b.Failure, want: false, wantState: breakerClosed},
+		{name: "threshold opens", do: b.Failure, want: true, wantState: breakerOpen},
+		{name: "open rejects", do: b.Allow, want: false, wantState: breakerOpen},
+		{name: "probe after cooldown", do: b.Allow, want: true, wantState: breakerHalfOpen, waitBefore: cooldown},
+		{name: "one probe at a time", do: b.Allow, want: false, wantState: breakerHalfOpen},
+		{name: "aborted probe", do: abort(b), wantState: breakerOpen},
+		{name: "probe retried", do: b.Allow, want: true, wantState: breakerHalfOpen},
+		{name: "failed probe reopens quietly", do: b.Failure, want: false, wantState: breakerOpen},
+		{name: "second probe", do: b.Allow, want: true, wantState: breakerHalfOpen, waitBefore: cooldown},
+		{name: "probe succeeds", do: success(b), wantState: breakerClosed},
+		{name: "failures reset", do: b.Failure, want: false, wantState: breakerClosed},
+		{name: "abort while closed", do: abort(b), wantState: breakerClosed},
+	}
+	for _, step := range steps {
+		time.Sleep(step.waitBefore)
+		if got := step.do(); got != step.want {
+			t.Errorf("%s: got %t, want %t", step.name, got, step.want)
+		}
+		if got := b.Status().State; got != step.wantState {
+			t.Fatalf("%s: state %s, want %s", step.name, got, step.wantState)
+		}
+	}
+}
+
+func abort(b *CircuitBreaker) func() bool {
+	return func() bool { b.Abort(); return false }
+}
+
+func success(b *CircuitBreaker) func() bool {
+	return func() bool { b.Success(); return false }
+}
+
+func TestIsUpstreamFailure(t *testing.T) {
+	cancelled, cancel := context.WithCancel(context.Background())
+	cancel()
+	expired, cancel := context.WithTimeout(context.Background(), -time.Second)
+	defer cancel()
+
+	tests := []struct {
+		name string
+		ctx  context.Context
+		err  error
+		want bool
+	}{
+		{"server error", context.Background(), &httpStatusError{StatusCode: 502}, true},
+		{"rate limited", context.Background(), &httpStatusError{StatusCode: 429}, true},
+		{"not found", context.Background(), &httpStatusError{StatusCode: 404}, false},
+		{"network error", context.Background(), &net.OpError{Op: "dial", Err: errors.New("connection refused")}, true},
+		{"truncated body", context.Background(), fmt.Errorf("read body: %w", io.ErrUnexpectedEOF), true},
+		{"parse error", context.Background(), errors.New("no prices found for sjc"), false},
+		{"caller gave up", cancelled, &net.OpError{Op: "read", Err: context.Canceled}, false},
+		{"caller gave up mid-request", cancelled, fmt.Errorf("read body: %w", context.Canceled), false},
+		{"deadline ran out", expired, context.DeadlineExceeded, true},
+		{"deadline ran out in the client", expired, fmt.Errorf("Get: %w", context.DeadlineExceeded), true},
+	}
+	for _, tt := range tests {
+		if got := isUpstreamFailure(tt.ctx, tt.err); got != tt.want {
+			t.Errorf("%s: isUpstreamFailure = %t, want %t", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestHangingSourceOpensBreaker(t *testing.T) {
+	src := &fakeSource{name: "fake", goldTypes: []string{"sjc"}}
+	src.fetch = func(ctx context.Context, goldType string) (*GoldPrice, error) {
+		<-ctx.Done() // never answers within the crawl timeout
+		return nil, ctx.Err()
+	}
+	useTestBackends(t, src)
+	c := NewCrawler(1, 20*time.Millisecond)
+
+	for i := range breakerThreshold {
+		if res := c.Run(context.Background(), []string{"sjc"}).Results[0]; res.OK {
+			t.Fatalf("crawl %d succeeded", i+1)
+		}
+	}
+	status := sources.Status()["fake"]
+	if status.State != breakerOpen || status.Failures != breakerThreshold {
+		t.Fatalf("breaker = %+v after %d timeouts, want it open", status, breakerThreshold)
+	}
+
+	res := c.Run(context.Background(), []string{"sjc"}).Results[0]
+	if !strings.Contains(res.Error, errCircuitOpen.Error()) {
+		t.Errorf("crawl with the breaker open = %+v, want %v", res, errCircuitOpen)
+	}
+	if n := src.calls.Load(); n != breakerThreshold {
+		t.Errorf("source called %d times, want %d", n, breakerThreshold)
+	}
+}
@@ -0,0 +1,55 @@
+package main
+
+import "slices"
+
+// Provider describes one gold type offered by the API.
+type Provider struct {
+	ID     string `json:"id"`
+	Name   string `json:"name"`
+	City   string `json:"city"`
+	Brand  string `json:"brand"`
+	Unit   string `json:"unit"`
+	Source string `json:"source"`
+}
+
+// providerCatalog holds the display metadata for every entry of GOLDTYPES.
+var providerCatalog = []Provider{
+	{ID: "sjc", Name: "SJC", City: "TP.HCM", Brand: "SJC", Unit: priceUnit, Source: "24h"},
+	{ID: "doji_hn", Name: "DOJI HN", City: "Hà Nội", Brand: "DOJI", Unit: priceUnit, Source: "24h"},
+	{ID: "doji_sg", Name: "DOJI SG", City: "TP.HCM", Brand: "DOJI", Unit: priceUnit, Source: "24h"},
+	{ID: "bao_tin_minh_chau", Name: "Bảo Tín Minh Châu", City: "Hà Nội", Brand: "Bảo Tín Minh Châu", Unit: priceUnit, Source: "24h"},
+	{ID: "phu_quy_sjc", Name: "Phú Quý SJC", City: "Hà Nội", Brand: "Phú Quý", Unit: priceUnit, Source: "24h"},
+	{ID: "pnj_tp_hcml", Name: "PNJ TP.HCM", City: "TP.HCM", Brand: "PNJ", Unit: priceUnit, Source: "24h"},
+	{ID: "pnj_hn", Name: "PNJ HN", City: "Hà Nội", Brand: "PNJ", Unit: priceUnit, Source: "24h"},
+}
+
+// findProvider looks up a gold type in the catalog.
+func findProvider(id string) (Provider, bool) {
+	for _, p := range providerCatalog {
+		if p.ID == id {
+			return p, true
+		}
+	}
+	return Provider{}, false
+}
+
+// enabledProvider looks up a gold type among those enabled by
+// crawl.gold_types. Every entry point that takes a gold type checks it here,
+// so a type left out of the config is neither served nor crawled.
+func enabledProvider(id string) (Provider, bool) {
+	if !slices.Contains(GOLDTYPES, id) {
+		return Provider{}, false
+	}
+	return findProvider(id)
+}
+
+// enabledProviders lists the enabled gold types in catalog order.
+func enabledProviders() []Provider {
+	var res []Provider
+	for _, p := range providerCatalog {
+		if slices.Contains(GOLDTYPES, p.ID) {
+			res = append(res, p)
+		}
+	}
+	return res
+}
@@ -0,0 +1,281 @@
+// Package chart draws buy/sell price lines as PNG images without any
+// external renderer.
+package chart
+
+import (
+	"errors"
+	"fmt"
+	"image"
+	"image/color"
+	"image/draw"
+	"image/png"
+	"io"
+	"math"
+	"strings"
+	"time"
+	"unicode"
+
+	"golang.org/x/image/font"
+	"golang.org/x/image/font/basicfont"
+	"golang.org/x/image/math/fixed"
+	"golang.org/x/text/runes"
+	"golang.org/x/text/transform"
+	"golang.org/x/text/unicode/norm"
+)
+
+// ErrNoData is returned by Render when no series has a point to draw.
+var ErrNoData = errors.New("chart: no data")
+
+// Point is one day of prices.
+type Point struct {
+	Date time.Time
+	Buy  float64
+	Sell float64
+}
+
+// Series is the price history of one provider, oldest first.
+type Series struct {
+	Name   string
+	Points []Point
+}
+
+// Options control the image. Zero values pick the defaults.
+type Options struct {
+	Title  string
+	Width  int // default 800
+	Height int // default 420
+	// Scale divides prices for the axis labels, e.g. 1e6 for millions.
+	Scale float64
+}
+
+const (
+	marginLeft   = 60
+	marginRight  = 20
+	marginTop    = 32 // title; legend rows are added below it
+	legendRow    = 16
+	marginBottom = 32
+	yTicks       = 5
+	xTicks       = 6
+)
+
+var (
+	background = color.RGBA{0xff, 0xff, 0xff, 0xff}
+	gridColor  = color.RGBA{0xe5, 0xe5, 0xe5, 0xff}
+	axisColor  = color.RGBA{0x66, 0x66, 0x66, 0xff}
+	textColor  = color.RGBA{0x22, 0x22, 0x22, 0xff}
+	palette    = []color.RGBA{
+		{0xd4, 0x8a, 0x00, 0xff},
+		{0x1f, 0x77, 0xb4, 0xff},
+		{0x2c, 0xa0, 0x2c, 0xff},
+		{0xd6, 0x27, 0x28, 0xff},
+		{0x94, 0x67, 0xbd, 0xff},
+		{0x8c, 0x56, 0x4b, 0xff},
+		{0x17, 0xbe, 0xcf, 0xff},
+	}
+)
+
+// Render draws one sell line (solid) and one buy line (dashed) per series
+// and writes the PNG to w.
+func Render(w io.Writer, series []Series, opts Options) error {
+	img, err := Draw(series, opts)
+	if err != nil {
+		return err
+	}
+	return png.Encode(w, img)
+}
+
+// Draw is Render without the PNG encoding.
+func Draw(series []Series, opts Options) (*image.RGBA, error) {
+	if opts.Width <= 0 {
+		opts.Width = 800
+	}
+	if opts.Height <= 0 {
+		opts.Height = 420
+	}
+	if opts.Scale <= 0 {
+		opts.Scale = 1
+	}
+
+	minT, maxT, minV, maxV, ok := bounds(series)
+	if !ok {
+		return nil, ErrNoData
+	}
+	if maxT.Equal(minT) {
+		minT = minT.AddDate(0, 0, -1)
+	}
+	pad := (maxV - minV) * 0.05
+	if pad == 0 {
+		pad = maxV * 0.01
+	}
+	minV, maxV = minV-pad, maxV+pad
+
+	img := image.NewRGBA(image.Rect(0, 0, opts.Width, opts.Height))
+	draw.Draw(img, img.Bounds(), &image.Uniform{background}, image.Point{}, draw.Src)
+
+	// Title and legend, wrapping the legend to as many rows as needed.
+	text(img, marginLeft, 18, asciiLabel(opts.Title))
+	lx, ly := marginLeft, marginTop
+	for i, s := range series {
+		if len(s.Points) == 0 {
+			continue
+		}
+		c := palette[i%len(palette)]
+		sell, buy := asciiLabel(s.Name)+" sell", "buy"
+		if w := 40 + textWidth(sell) + 12 + textWidth(buy) + 20; lx > marginLeft && lx+w > opts.Width-marginRight {
+			lx, ly = marginLeft, ly+legendRow
+		}
+		line(img, lx, ly-4, lx+16, ly-4, c, false)
+		text(img, lx+20, ly, sell)
+		lx += 20 + textWidth(sell) + 12
+		line(img, lx, ly-4, lx+16, ly-4, c, true)
+		text(img, lx+20, ly, buy)
+		lx += 20 + textWidth(buy) + 20
+	}
+
+	plot := image.Rect(marginLeft, ly+legendRow, opts.Width-marginRight, opts.Height-marginBottom)
+	x := func(t time.Time) int {
+		f := float64(t.Sub(minT)) / float64(maxT.Sub(minT))
+		return plot.Min.X + int(math.Round(f*float64(plot.Dx())))
+	}
+	y := func(v float64) int {
+		f := (v - minV) / (maxV - minV)
+		return plot.Max.Y - int(math.Round(f*float64(plot.Dy())))
+	}
+
+	// Grid and axis labels.
+	for i := 0; i <= yTicks; i++ {
+		v := minV + (maxV-minV)*float64(i)/yTicks
+		py := y(v)
+		hline(img, plot.Min.X, plot.Max.X, py, gridColor)
+		label := fmt.Sprintf("%.1f", v/opts.Scale)
+		text(img, plot.Min.X-6-textWidth(label), py+4, label)
+	}
+	days := int(maxT.Sub(minT).Hours()/24 + 0.5)
+	step := max(1, int(math.Ceil(float64(days)/xTicks)))
+	for d := 0; d <= days; d += step {
+		t := minT.AddDate(0, 0, d)
+		px := x(t)
+		vline(img, px, plot.Min.Y, plot.Max.Y, gridColor)
+		label := t.Format("02/01")
+		text(img, px-textWidth(label)/2, plot.Max.Y+16, label)
+	}
+	hline(img, plot.Min.X, plot.Max.X, plot.Max.Y, axisColor)
+	vline(img, plot.Min.X, plot.Min.Y, plot.Max.Y, axisColor)
+
+	// Lines.
+	for i, s := range series {
+		c := palette[i%len(palette)]
+		for j := 1; j < len(s.Points); j++ {
+			a, b := s.Points[j-1], s.Points[j]
+			if a.Sell > 0 && b.Sell > 0 {
+				line(img, x(a.Date), y(a.Sell), x(b.Date), y(b.Sell), c, false)
+			}
+			if a.Buy > 0 && b.Buy > 0 {
+				line(img, x(a.Date), y(a.Buy), x(b.Date), y(b.Buy), c, true)
+			}
+		}
+	}
+	return img, nil
+}
+
+func bounds(series []Series) (minT, maxT time.Time, minV, maxV float64, ok bool) {
+	minV, maxV = math.Inf(1), math.Inf(-1)
+	for _, s := range series {
+		for _, p := range s.Points {
+			for _, v := range []float64{p.Buy, p.Sell} {
+				if v <= 0 {
+					continue
+				}
+				minV, maxV = math.Min(minV, v), math.Max(maxV, v)
+				ok = true
+			}
+			if minT.IsZero() || p.Date.Before(minT) {
+				minT = p.Date
+			}
+			if p.Date.After(maxT) {
+				maxT = p.Date
+			}
+		}
+	}
+	return minT, maxT, minV, maxV, ok
+}
+
+func hline(img *image.RGBA, x0, x1, y int, c color.Color) {
+	for x := x0; x <= x1; x++ {
+		img.Set(x, y, c)
+	}
+}
+
+func vline(img *image.RGBA, x, y0, y1 int, c color.Color) {
+	for y := y0; y <= y1; y++ {
+		img.Set(x, y, c)
+	}
+}
+
+// line draws a 2px wide segment with Bresenham's algorithm, skipping every
+// other run of pixels when dashed.
+func line(img *image.RGBA, x0, y0, x1, y1 int, c color.Color, dashed bool) {
+	dx, dy := abs(x1-x0), -abs(y1-y0)
+	sx, sy := sign(x1-x0), sign(y1-y0)
+	e := dx + dy
+	for n := 0; ; n++ {
+		if !dashed || (n/6)%2 == 0 {
+			img.Set(x0, y0, c)
+			img.Set(x0+1, y0, c)
+			img.Set(x0, y0+1, c)
+			img.Set(x0+1, y0+1, c)
+		}
+		if x0 == x1 && y0 == y1 {
+			return
+		}
+		if e2 := 2 * e; e2 >= dy {
+			e += dy
+			x0 += sx
+		} else {
+			e += dx
+			y0 += sy
+		}
+	}
+}
+
+func text(img *image.RGBA, x, y int, s string) {
+	d := &font.Drawer{
+		Dst:  img,
+		Src:  &image.Uniform{textColor},
+		Face: basicfont.Face7x13,
+		Dot:  fixed.P(x, y),
+	}
+	d.DrawString(s)
+}
+
+func textWidth(s string) int {
+	return font.MeasureString(basicfont.Face7x13, s).Round()
+}
+
+// asciiLabel drops Vietnamese diacritics, which the bitmap font cannot draw.
+func asciiLabel(s string) string {
+	s = strings.NewReplacer("đ", "d", "Đ", "D").Replace(s)
+	t := transform.Chain(norm.NFD, runes.Remove(runes.In(unicode.Mn)), norm.NFC)
+	out, _, err := transform.String(t, s)
+	if err != nil {
+		return s
+	}
+	return out
+}
+
+func abs(v int) int {
+	if v < 0 {
+		return -v
+	}
+	return v
+}
+
+func sign(v int) int {
+	switch {
+	case v > 0:
+		return 1
+	case v < 0:
+		return -1
+	}
+	return 0
+}
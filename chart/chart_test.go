@@ -0,0 +1,152 @@
+package chart
+
+import (
+	"bytes"
+	"errors"
+	"image"
+	"image/png"
+	"testing"
+	"time"
+)
+
+func day(d int) time.Time {
+	return time.Date(2025, 3, d, 0, 0, 0, 0, time.UTC)
+}
+
+func TestDrawNoData(t *testing.T) {
+	tests := []struct {
+		name   string
+		series []Series
+	}{
+		{"nil", nil},
+		{"no points", []Series{{Name: "SJC"}}},
+		{"no prices", []Series{{Name: "SJC", Points: []Point{{Date: day(1)}, {Date: day(2)}}}}},
+	}
+	for _, tt := range tests {
+		if _, err := Draw(tt.series, Options{}); !errors.Is(err, ErrNoData) {
+			t.Errorf("%s: Draw = %v, want ErrNoData", tt.name, err)
+		}
+		if err := Render(new(bytes.Buffer), tt.series, Options{}); !errors.Is(err, ErrNoData) {
+			t.Errorf("%s: Render = %v, want ErrNoData", tt.name, err)
+		}
+	}
+}
+
+// plotLines counts the horizontal bands of the plot area holding pixels of
+// the first series' color, below a one-row legend and inside the axes. A
+// line drawn across the plot is one band however thick it is.
+func plotLines(img *image.RGBA) int {
+	b := img.Bounds()
+	bands, inBand := 0, false
+	for y := marginTop + legendRow + 1; y < b.Max.Y-marginBottom; y++ {
+		hit := false
+		for x := marginLeft + 1; x < b.Max.X-marginRight && !hit; x++ {
+			hit = img.RGBAAt(x, y) == palette[0]
+		}
+		if hit && !inBand {
+			bands++
+		}
+		inBand = hit
+	}
+	return bands
+}
+
+func TestDrawEdgeCases(t *testing.T) {
+	tests := []struct {
+		name   string
+		series []Series
+		opts   Options
+		width  int
+		height int
+		bands  int
+	}{
+		{
+			name:   "one point",
+			series: []Series{{Name: "SJC", Points: []Point{{Date: day(16), Buy: 118e6, Sell: 120e6}}}},
+			width:  800,
+			height: 420,
+			bands:  0, // nothing to join
+		},
+		{
+			name: "flat series",
+			series: []Series{{Name: "SJC", Points: []Point{
+				{Date: day(14), Buy: 118e6, Sell: 120e6},
+				{Date: day(15), Buy: 118e6, Sell: 120e6},
+				{Date: day(16), Buy: 118e6, Sell: 120e6},
+			}}},
+			opts:   Options{Width: 400, Height: 300, Scale: 1e6},
+			width:  400,
+			height: 300,
+			bands:  2, // buy and sell, both inside the axes
+		},
+		{
+			name: "flat and equal",
+			series: []Series{{Name: "SJC", Points: []Point{
+				{Date: day(15), Buy: 120e6, Sell: 120e6},
+				{Date: day(16), Buy: 120e6, Sell: 120e6},
+			}}},
+			width:  800,
+			height: 420,
+			bands:  1,
+		},
+		{
+			name: "gap and an empty series",
+			series: []Series{
+				{Name: "SJC", Points: []Point{
+					{Date: day(1), Buy: 118e6, Sell: 120e6},
+					{Date: day(2)},
+					{Date: day(3), Buy: 119e6, Sell: 121e6},
+				}},
+				{Name: "DOJI"},
+			},
+			width:  800,
+			height: 420,
+			bands:  0, // no two neighbours have prices
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			img, err := Draw(tt.series, tt.opts)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if b := img.Bounds(); b.Dx() != tt.width || b.Dy() != tt.height {
+				t.Errorf("image is %dx%d, want %dx%d", b.Dx(), b.Dy(), tt.width, tt.height)
+			}
+			if n := plotLines(img); n != tt.bands {
+				t.Errorf("series drawn as %d horizontal bands, want %d", n, tt.bands)
+			}
+		})
+	}
+}
+
+func TestRenderPNG(t *testing.T) {
+	series := []Series{{Name: "Bảo Tín Minh Châu", Points: []Point{
+		{Date: day(15), Buy: 118e6, Sell: 120e6},
+		{Date: day(16), Buy: 118.5e6, Sell: 121e6},
+	}}}
+	var buf bytes.Buffer
+	if err := Render(&buf, series, Options{Title: "Giá vàng (triệu đồng/lượng)", Width: 640, Height: 360, Scale: 1e6}); err != nil {
+		t.Fatal(err)
+	}
+	img, err := png.Decode(&buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if b := img.Bounds(); b.Dx() != 640 || b.Dy() != 360 {
+		t.Errorf("PNG is %dx%d, want 640x360", b.Dx(), b.Dy())
+	}
+}
+
+func TestAsciiLabel(t *testing.T) {
+	tests := []struct{ in, want string }{
+		{"Bảo Tín Minh Châu", "Bao Tin Minh Chau"},
+		{"Đơn vị", "Don vi"},
+		{"SJC 24K", "SJC 24K"},
+	}
+	for _, tt := range tests {
+		if got := asciiLabel(tt.in); got != tt.want {
+			t.Errorf("asciiLabel(%q) = %q, want %q", tt.in, got, tt.want)
+		}
+	}
+}
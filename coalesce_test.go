@@ -0,0 +1,173 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestFlightGroupSharesOneCall(t *testing.T) {
+	var g flightGroup
+	var calls atomic.Int32
+	release := make(chan struct{})
+	fail := errors.New("upstream down")
+	fn := func() error {
+		calls.Add(1)
+		<-release
+		return fail
+	}
+
+	const callers = 5
+	errs := make(chan error, callers)
+	var started sync.WaitGroup
+	for range callers {
+		started.Add(1)
+		go func() {
+			started.Done()
+			errs <- g.Do(context.Background(), "sjc", fn)
+		}()
+	}
+	started.Wait()
+	waitForCalls(&g, 1)
+	time.Sleep(50 * time.Millisecond) // let the others join the flight
+	close(release)
+
+	for range callers {
+		if err := <-errs; !errors.Is(err, fail) {
+			t.Errorf("Do = %v, want the shared error", err)
+		}
+	}
+	if n := calls.Load(); n != 1 {
+		t.Errorf("fn ran %d times, want once", n)
+	}
+
+	// Once the flight has landed the next call runs fn again.
+	if err := g.Do(context.Background(), "sjc", func() error { calls.Add(1); return nil }); err != nil {
+		t.Fatal(err)
+	}
+	if n := calls.Load(); n != 2 {
+		t.Errorf("fn ran %d times after the flight, want 2", n)
+	}
+}
+
+func TestFlightGroupWaiterGivesUp(t *testing.T) {
+	var g flightGroup
+	release := make(chan struct{})
+	finished := make(chan struct{})
+	fn := func() error {
+		<-release
+		close(finished)
+		return nil
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if err := g.Do(ctx, "sjc", fn); !errors.Is(err, context.Canceled) {
+		t.Fatalf("Do with a cancelled context = %v, want context.Canceled", err)
+	}
+
+	// A second caller joins the same flight, which the first one leaving
+	// did not abort.
+	done := make(chan error, 1)
+	go func() { done <- g.Do(context.Background(), "sjc", func() error { return errors.New("ran twice") }) }()
+	time.Sleep(50 * time.Millisecond)
+	close(release)
+	if err := <-done; err != nil {
+		t.Errorf("second caller = %v, want the first flight's result", err)
+	}
+	<-finished
+}
+
+// waitForCalls blocks until n keys are in flight in g.
+func waitForCalls(g *flightGroup, n int) {
+	for {
+		g.mu.Lock()
+		got := len(g.calls)
+		g.mu.Unlock()
+		if got == n {
+			return
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+func TestLoadGoldPriceRevalidatesStaleSnapshots(t *testing.T) {
+	tests := []struct {
+		name        string
+		age         time.Duration
+		wantRefresh bool
+	}{
+		{"fresh", time.Minute, false},
+		{"stale", 2 * time.Hour, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			refreshed := make(chan struct{}, 1)
+			src := &fakeSource{name: "fake", goldTypes: []string{"sjc"}}
+			src.fetch = func(ctx context.Context, goldType string) (*GoldPrice, error) {
+				defer func() { refreshed <- struct{}{} }()
+				return todaysPrice(goldType), nil
+			}
+			useTestBackends(t, src)
+			useTestCrawler(t, time.Hour)
+
+			old := todaysPrice("sjc")
+			old.BuyPrices[0] = 110e6
+			old.UpdatedAt = time.Now().Add(-tt.age)
+			if err := store.SaveGoldPrice(context.Background(), "sjc", old); err != nil {
+				t.Fatal(err)
+			}
+
+			got, err := loadGoldPrice("sjc")
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got.BuyPrices[0] != 110e6 {
+				t.Errorf("loadGoldPrice waited for the refresh: buy %g", got.BuyPrices[0])
+			}
+
+			if !tt.wantRefresh {
+				waitForFlights(crawler)
+				if n := src.calls.Load(); n != 0 {
+					t.Errorf("a fresh snapshot was refetched %d times", n)
+				}
+				return
+			}
+			select {
+			case <-refreshed:
+			case <-time.After(time.Second):
+				t.Fatal("a stale snapshot was not refreshed")
+			}
+			waitForFlights(crawler)
+			if got, _ := store.GetGoldPrice(context.Background(), "sjc"); got.BuyPrices[0] != 118e6 {
+				t.Errorf("store after the refresh has buy %g, want 118e6", got.BuyPrices[0])
+			}
+		})
+	}
+}
+
+func TestLoadGoldPriceCrawlsMissingSnapshots(t *testing.T) {
+	src := &fakeSource{name: "fake", goldTypes: []string{"sjc"}}
+	useTestBackends(t, src)
+	useTestCrawler(t, time.Hour)
+
+	got, err := loadGoldPrice("sjc")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.SellPrices[0] != 120e6 || src.calls.Load() != 1 {
+		t.Errorf("loadGoldPrice = %+v after %d fetches, want the crawled snapshot", got, src.calls.Load())
+	}
+}
+
+// useTestCrawler swaps the crawler for one with a one-second timeout and sets
+// staleAfter until the test ends.
+func useTestCrawler(t *testing.T, stale time.Duration) {
+	t.Helper()
+	oldCrawler, oldStale := crawler, staleAfter
+	crawler, staleAfter = NewCrawler(1, time.Second), stale
+	t.Cleanup(func() { crawler, staleAfter = oldCrawler, oldStale })
+}
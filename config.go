@@ -0,0 +1,562 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"net/mail"
+	"net/url"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	bottelegram "pricegoldtoday/bot"
+
+	"github.com/robfig/cron/v3"
+	"gopkg.in/yaml.v3"
+)
+
+// Duration is a time.Duration written as a string such as "20s" in config
+// files.
+type Duration struct {
+	time.Duration
+}
+
+func (d Duration) MarshalText() ([]byte, error) {
+	return []byte(d.String()), nil
+}
+
+func (d *Duration) UnmarshalText(text []byte) error {
+	v, err := time.ParseDuration(string(text))
+	if err != nil {
+		return err
+	}
+	d.Duration = v
+	return nil
+}
+
+// Config is the complete service configuration.
+type Config struct {
+	HTTP      HTTPConfig      `yaml:"http" json:"http"`
+	Store     StoreConfig     `yaml:"store" json:"store"`
+	Crawl     CrawlConfig     `yaml:"crawl" json:"crawl"`
+	Telegram  TelegramConfig  `yaml:"telegram" json:"telegram"`
+	Alerts    AlertsConfig    `yaml:"alerts" json:"alerts"`
+	Notifiers NotifiersConfig `yaml:"notifiers" json:"notifiers"`
+}
+
+type HTTPConfig struct {
+	Addr string `yaml:"addr" json:"addr"`
+}
+
+type StoreConfig struct {
+	Backend string      `yaml:"backend" json:"backend"` // redis, file or memory
+	Path    string      `yaml:"path" json:"path"`       // file backend only
+	Redis   RedisConfig `yaml:"redis" json:"redis"`
+}
+
+type RedisConfig struct {
+	Addr     string `yaml:"addr" json:"addr"`
+	Password string `yaml:"password" json:"password"`
+	DB       int    `yaml:"db" json:"db"`
+}
+
+type CrawlConfig struct {
+	Schedule    string   `yaml:"schedule" json:"schedule"`
+	Concurrency int      `yaml:"concurrency" json:"concurrency"`
+	Timeout     Duration `yaml:"timeout" json:"timeout"`
+	StaleAfter  Duration `yaml:"stale_after" json:"stale_after"`
+	GoldTypes   []string `yaml:"gold_types" json:"gold_types"`
+}
+
+type TelegramConfig struct {
+	BotToken string `yaml:"bot_token" json:"bot_token"`
+	ChatID   string `yaml:"chat_id" json:"chat_id"`
+	// Schedule is the default digest schedule given to new subscribers.
+	Schedule string `yaml:"schedule" json:"schedule"`
+	// Language and Unit are the defaults for new subscribers: a locale with
+	// a digest template, and "luong" or "chi".
+	Language string `yaml:"language" json:"language"`
+	Unit     string `yaml:"unit" json:"unit"`
+	// TemplatesDir holds digest.<locale>.tmpl files that replace the
+	// built-in templates or add locales.
+	TemplatesDir string `yaml:"templates_dir" json:"templates_dir"`
+	// Notify is "changes" to send the digest only when prices changed, or
+	// "always" to send it on every schedule tick.
+	Notify string `yaml:"notify" json:"notify"`
+	// DailyDigest is a cron spec at which the digest is sent even when
+	// nothing changed; empty disables it.
+	DailyDigest string `yaml:"daily_digest" json:"daily_digest"`
+	// Mode is "polling" to answer commands via getUpdates, "webhook" to
+	// receive them on the HTTP server, or "push" to only send digests.
+	Mode    string        `yaml:"mode" json:"mode"`
+	Webhook WebhookConfig `yaml:"webhook" json:"webhook"`
+	// Required makes a missing token or chat ID fatal instead of disabling
+	// the bot.
+	Required bool `yaml:"required" json:"required"`
+	// APIURL overrides the Bot API endpoint, e.g. for a self-hosted
+	// telegram-bot-api server.
+	APIURL string `yaml:"api_url" json:"api_url"`
+	// ConfigFile is the legacy bot JSON config; it only fills in values
+	// not set above.
+	ConfigFile string `yaml:"config_file" json:"config_file"`
+}
+
+// AlertsConfig tunes how alert rules are deduplicated.
+type AlertsConfig struct {
+	// Cooldown is the minimum time between two firings of the same rule.
+	Cooldown Duration `yaml:"cooldown" json:"cooldown"`
+	// Hysteresis is how far, as a fraction of the threshold, the value must
+	// retreat before a fired rule can fire again.
+	Hysteresis float64 `yaml:"hysteresis" json:"hysteresis"`
+}
+
+// NotifiersConfig lists the outbound sinks for price and alert events.
+type NotifiersConfig struct {
+	// DeadLetter is a JSON Lines file receiving events no notifier could
+	// deliver; empty disables it.
+	DeadLetter string `yaml:"dead_letter" json:"dead_letter"`
+	// Timeout bounds the delivery of one event, retries included.
+	Timeout Duration `yaml:"timeout" json:"timeout"`
+	// DigestSchedule and WeeklySchedule are the cron specs of the
+	// digest.daily and digest.weekly events; empty disables them.
+	DigestSchedule string                  `yaml:"digest_schedule" json:"digest_schedule"`
+	WeeklySchedule string                  `yaml:"weekly_schedule" json:"weekly_schedule"`
+	Webhooks       []WebhookNotifierConfig `yaml:"webhooks" json:"webhooks"`
+	Discord        []ChatNotifierConfig    `yaml:"discord" json:"discord"`
+	Slack          []ChatNotifierConfig    `yaml:"slack" json:"slack"`
+	Email          []EmailNotifierConfig   `yaml:"email" json:"email"`
+}
+
+// EmailNotifierConfig is an SMTP server and the recipients of the digest
+// emails. STARTTLS is used when the server offers it.
+type EmailNotifierConfig struct {
+	Name     string   `yaml:"name" json:"name"`
+	Host     string   `yaml:"host" json:"host"`
+	Port     int      `yaml:"port" json:"port"` // default 587
+	Username string   `yaml:"username" json:"username"`
+	Password string   `yaml:"password" json:"password"`
+	From     string   `yaml:"from" json:"from"`
+	To       []string `yaml:"to" json:"to"`
+	// Events defaults to digest.daily and digest.weekly.
+	Events   []string `yaml:"events" json:"events"`
+	Language string   `yaml:"language" json:"language"` // vi or en
+	Unit     string   `yaml:"unit" json:"unit"`         // luong or chi
+}
+
+// ChatNotifierConfig is a Discord or Slack incoming webhook.
+type ChatNotifierConfig struct {
+	Name string `yaml:"name" json:"name"`
+	URL  string `yaml:"url" json:"url"`
+	// Events defaults to digest.daily and alert.fired.
+	Events   []string `yaml:"events" json:"events"`
+	Language string   `yaml:"language" json:"language"` // vi or en
+	Unit     string   `yaml:"unit" json:"unit"`         // luong or chi
+	Timeout  Duration `yaml:"timeout" json:"timeout"`   // per request, default 10s
+}
+
+type WebhookNotifierConfig struct {
+	Name   string `yaml:"name" json:"name"`
+	URL    string `yaml:"url" json:"url"`
+	Secret string `yaml:"secret" json:"secret"`
+	// Events limits delivery to these event types; empty means all.
+	Events  []string `yaml:"events" json:"events"`
+	Timeout Duration `yaml:"timeout" json:"timeout"` // per request, default 10s
+}
+
+// WebhookConfig is used in webhook mode and by the "webhook" subcommand.
+type WebhookConfig struct {
+	// URL is the public HTTPS address Telegram posts to, as seen through the
+	// reverse proxy.
+	URL string `yaml:"url" json:"url"`
+	// Path is where the HTTP server mounts the endpoint.
+	Path   string `yaml:"path" json:"path"`
+	Secret string `yaml:"secret" json:"secret"`
+}
+
+func defaultConfig() Config {
+	return Config{
+		HTTP: HTTPConfig{Addr: ":8080"},
+		Store: StoreConfig{
+			Backend: "redis",
+			Path:    "data/gold.json",
+			Redis:   RedisConfig{Addr: "localhost:6379"},
+		},
+		Crawl: CrawlConfig{
+			Schedule:    "0 */6 * * *",
+			Concurrency: 4,
+			Timeout:     Duration{20 * time.Second},
+			StaleAfter:  Duration{6 * time.Hour},
+			GoldTypes:   append([]string(nil), GOLDTYPES...),
+		},
+		Telegram: TelegramConfig{
+			Schedule:    "@every 1m",
+			Language:    "vi",
+			Unit:        bottelegram.UnitLuong,
+			Notify:      "changes",
+			DailyDigest: "CRON_TZ=Asia/Ho_Chi_Minh 0 8 * * *",
+			Mode:        "polling",
+			Webhook:     WebhookConfig{Path: "/telegram/webhook"},
+		},
+		Alerts: AlertsConfig{
+			Cooldown:   Duration{time.Hour},
+			Hysteresis: 0.005,
+		},
+		Notifiers: NotifiersConfig{
+			DeadLetter:     "data/dead_letter.jsonl",
+			Timeout:        Duration{time.Minute},
+			DigestSchedule: "CRON_TZ=Asia/Ho_Chi_Minh 0 8 * * *",
+			WeeklySchedule: "CRON_TZ=Asia/Ho_Chi_Minh 0 8 * * 1",
+		},
+	}
+}
+
+// loadConfig builds the configuration from, in increasing precedence: the
+// defaults, the config file, environment variables and command-line flags.
+func loadConfig(args []string) (*Config, error) {
+	cfg := defaultConfig()
+
+	fs := flag.NewFlagSet("pricegoldtoday", flag.ContinueOnError)
+	configFile := fs.String("config", os.Getenv("CONFIG_FILE"), "path to a YAML or JSON config file")
+	httpAddr := fs.String("http-addr", "", "HTTP listen address")
+	storeBackend := fs.String("store", "", "store backend: redis, file or memory")
+	storePath := fs.String("store-path", "", "data file for the file store")
+	redisAddr := fs.String("redis-addr", "", "Redis address")
+	crawlSchedule := fs.String("crawl-schedule", "", "cron spec for the crawl job")
+	goldTypes := fs.String("gold-types", "", "comma-separated gold types to crawl")
+	if err := fs.Parse(args); err != nil {
+		return nil, err
+	}
+
+	if *configFile != "" {
+		if err := cfg.loadFile(*configFile); err != nil {
+			return nil, err
+		}
+	}
+	if err := cfg.applyEnv(); err != nil {
+		return nil, err
+	}
+
+	setString(&cfg.HTTP.Addr, *httpAddr)
+	setString(&cfg.Store.Backend, *storeBackend)
+	setString(&cfg.Store.Path, *storePath)
+	setString(&cfg.Store.Redis.Addr, *redisAddr)
+	setString(&cfg.Crawl.Schedule, *crawlSchedule)
+	if *goldTypes != "" {
+		cfg.Crawl.GoldTypes = splitList(*goldTypes)
+	}
+
+	if err := cfg.Validate(); err != nil {
+		return nil, fmt.Errorf("invalid configuration: %w", err)
+	}
+	return &cfg, nil
+}
+
+func (c *Config) loadFile(path string) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".json":
+		err = json.Unmarshal(data, c)
+	case ".yaml", ".yml":
+		err = yaml.Unmarshal(data, c)
+	default:
+		return fmt.Errorf("unsupported config file type %q", path)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to parse %s: %w", path, err)
+	}
+	return nil
+}
+
+func (c *Config) applyEnv() error {
+	setString(&c.HTTP.Addr, os.Getenv("HTTP_ADDR"))
+	setString(&c.Store.Backend, os.Getenv("STORE_BACKEND"))
+	setString(&c.Store.Path, os.Getenv("STORE_PATH"))
+	setString(&c.Store.Redis.Addr, os.Getenv("REDIS_ADDR"))
+	setString(&c.Store.Redis.Password, os.Getenv("REDIS_PASSWORD"))
+	setString(&c.Crawl.Schedule, os.Getenv("CRAWL_SCHEDULE"))
+	setString(&c.Telegram.BotToken, os.Getenv("TELEGRAM_BOT_TOKEN"))
+	setString(&c.Telegram.ChatID, os.Getenv("TELEGRAM_CHAT_ID"))
+	setString(&c.Telegram.Schedule, os.Getenv("TELEGRAM_SCHEDULE"))
+	setString(&c.Telegram.Language, os.Getenv("TELEGRAM_LANGUAGE"))
+	setString(&c.Telegram.Unit, os.Getenv("TELEGRAM_UNIT"))
+	setString(&c.Telegram.TemplatesDir, os.Getenv("TELEGRAM_TEMPLATES_DIR"))
+	setString(&c.Telegram.Notify, os.Getenv("TELEGRAM_NOTIFY"))
+	setString(&c.Telegram.DailyDigest, os.Getenv("TELEGRAM_DAILY_DIGEST"))
+	setString(&c.Telegram.Mode, os.Getenv("TELEGRAM_MODE"))
+	setString(&c.Telegram.Webhook.URL, os.Getenv("TELEGRAM_WEBHOOK_URL"))
+	setString(&c.Telegram.Webhook.Path, os.Getenv("TELEGRAM_WEBHOOK_PATH"))
+	setString(&c.Telegram.Webhook.Secret, os.Getenv("TELEGRAM_WEBHOOK_SECRET"))
+	setString(&c.Telegram.APIURL, os.Getenv("TELEGRAM_API_URL"))
+	setString(&c.Telegram.ConfigFile, os.Getenv("TELEGRAM_CONFIG"))
+	if v := os.Getenv("GOLD_TYPES"); v != "" {
+		c.Crawl.GoldTypes = splitList(v)
+	}
+
+	setString(&c.Notifiers.DeadLetter, os.Getenv("NOTIFY_DEAD_LETTER"))
+	if v := os.Getenv("NOTIFY_WEBHOOK_URL"); v != "" {
+		c.Notifiers.Webhooks = append(c.Notifiers.Webhooks, WebhookNotifierConfig{
+			Name:   "env",
+			URL:    v,
+			Secret: os.Getenv("NOTIFY_WEBHOOK_SECRET"),
+		})
+	}
+	setString(&c.Notifiers.DigestSchedule, os.Getenv("NOTIFY_DIGEST_SCHEDULE"))
+	setString(&c.Notifiers.WeeklySchedule, os.Getenv("NOTIFY_WEEKLY_SCHEDULE"))
+	if v := os.Getenv("DISCORD_WEBHOOK_URL"); v != "" {
+		c.Notifiers.Discord = append(c.Notifiers.Discord, ChatNotifierConfig{Name: "discord-env", URL: v})
+	}
+	if v := os.Getenv("SLACK_WEBHOOK_URL"); v != "" {
+		c.Notifiers.Slack = append(c.Notifiers.Slack, ChatNotifierConfig{Name: "slack-env", URL: v})
+	}
+	if v := os.Getenv("SMTP_HOST"); v != "" {
+		email := EmailNotifierConfig{
+			Name:     "email-env",
+			Host:     v,
+			Username: os.Getenv("SMTP_USERNAME"),
+			Password: os.Getenv("SMTP_PASSWORD"),
+			From:     os.Getenv("SMTP_FROM"),
+			To:       splitList(os.Getenv("EMAIL_TO")),
+		}
+		if p := os.Getenv("SMTP_PORT"); p != "" {
+			port, err := strconv.Atoi(p)
+			if err != nil {
+				return fmt.Errorf("invalid SMTP_PORT %q: %w", p, err)
+			}
+			email.Port = port
+		}
+		c.Notifiers.Email = append(c.Notifiers.Email, email)
+	}
+
+	if v := os.Getenv("ALERT_HYSTERESIS"); v != "" {
+		f, err := strconv.ParseFloat(v, 64)
+		if err != nil {
+			return fmt.Errorf("invalid ALERT_HYSTERESIS %q: %w", v, err)
+		}
+		c.Alerts.Hysteresis = f
+	}
+
+	if v := os.Getenv("TELEGRAM_REQUIRED"); v != "" {
+		b, err := strconv.ParseBool(v)
+		if err != nil {
+			return fmt.Errorf("invalid TELEGRAM_REQUIRED %q: %w", v, err)
+		}
+		c.Telegram.Required = b
+	}
+
+	ints := map[string]*int{
+		"REDIS_DB":          &c.Store.Redis.DB,
+		"CRAWL_CONCURRENCY": &c.Crawl.Concurrency,
+	}
+	for name, dst := range ints {
+		if v := os.Getenv(name); v != "" {
+			n, err := strconv.Atoi(v)
+			if err != nil {
+				return fmt.Errorf("invalid %s %q: %w", name, v, err)
+			}
+			*dst = n
+		}
+	}
+
+	durations := map[string]*Duration{
+		"CRAWL_TIMEOUT":     &c.Crawl.Timeout,
+		"CACHE_STALE_AFTER": &c.Crawl.StaleAfter,
+		"ALERT_COOLDOWN":    &c.Alerts.Cooldown,
+	}
+	for name, dst := range durations {
+		if v := os.Getenv(name); v != "" {
+			if err := dst.UnmarshalText([]byte(v)); err != nil {
+				return fmt.Errorf("invalid %s %q: %w", name, v, err)
+			}
+		}
+	}
+	return nil
+}
+
+// Validate reports every problem with the configuration at once.
+func (c *Config) Validate() error {
+	var errs []error
+
+	if c.HTTP.Addr == "" {
+		errs = append(errs, errors.New("http.addr is required"))
+	}
+
+	switch c.Store.Backend {
+	case "redis":
+		if c.Store.Redis.Addr == "" {
+			errs = append(errs, errors.New("store.redis.addr is required for the redis backend"))
+		}
+	case "file":
+		if c.Store.Path == "" {
+			errs = append(errs, errors.New("store.path is required for the file backend"))
+		}
+	case "memory":
+	default:
+		errs = append(errs, fmt.Errorf("store.backend %q must be redis, file or memory", c.Store.Backend))
+	}
+
+	if _, err := cron.ParseStandard(c.Crawl.Schedule); err != nil {
+		errs = append(errs, fmt.Errorf("crawl.schedule: %w", err))
+	}
+	if c.Crawl.Concurrency < 1 {
+		errs = append(errs, errors.New("crawl.concurrency must be at least 1"))
+	}
+	if c.Crawl.Timeout.Duration <= 0 {
+		errs = append(errs, errors.New("crawl.timeout must be positive"))
+	}
+	if c.Crawl.StaleAfter.Duration < 0 {
+		errs = append(errs, errors.New("crawl.stale_after must not be negative"))
+	}
+	if len(c.Crawl.GoldTypes) == 0 {
+		errs = append(errs, errors.New("crawl.gold_types must not be empty"))
+	}
+	for _, goldType := range c.Crawl.GoldTypes {
+		if _, ok := findProvider(goldType); !ok {
+			errs = append(errs, fmt.Errorf("crawl.gold_types: unknown gold type %q", goldType))
+		}
+	}
+
+	if _, err := cron.ParseStandard(c.Telegram.Schedule); err != nil {
+		errs = append(errs, fmt.Errorf("telegram.schedule: %w", err))
+	}
+	if c.Telegram.Language == "" {
+		errs = append(errs, errors.New("telegram.language is required"))
+	}
+	if !bottelegram.ValidUnit(c.Telegram.Unit) {
+		errs = append(errs, fmt.Errorf("telegram.unit %q must be luong or chi", c.Telegram.Unit))
+	}
+	switch c.Telegram.Notify {
+	case notifyChanges, notifyAlways:
+	default:
+		errs = append(errs, fmt.Errorf("telegram.notify %q must be changes or always", c.Telegram.Notify))
+	}
+	if c.Telegram.DailyDigest != "" {
+		if _, err := cron.ParseStandard(c.Telegram.DailyDigest); err != nil {
+			errs = append(errs, fmt.Errorf("telegram.daily_digest: %w", err))
+		}
+	}
+	switch c.Telegram.Mode {
+	case "push", "polling":
+	case "webhook":
+		if !strings.HasPrefix(c.Telegram.Webhook.Path, "/") {
+			errs = append(errs, errors.New("telegram.webhook.path must start with /"))
+		}
+		if err := bottelegram.ValidateSecretToken(c.Telegram.Webhook.Secret); err != nil {
+			errs = append(errs, fmt.Errorf("telegram.webhook.secret: %w", err))
+		}
+	default:
+		errs = append(errs, fmt.Errorf("telegram.mode %q must be push, polling or webhook", c.Telegram.Mode))
+	}
+
+	if c.Alerts.Cooldown.Duration < 0 {
+		errs = append(errs, errors.New("alerts.cooldown must not be negative"))
+	}
+	if c.Alerts.Hysteresis < 0 || c.Alerts.Hysteresis >= 0.5 {
+		errs = append(errs, errors.New("alerts.hysteresis must be between 0 and 0.5"))
+	}
+
+	if c.Notifiers.Timeout.Duration <= 0 {
+		errs = append(errs, errors.New("notifiers.timeout must be positive"))
+	}
+	for i, w := range c.Notifiers.Webhooks {
+		if u, err := url.Parse(w.URL); err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			errs = append(errs, fmt.Errorf("notifiers.webhooks[%d].url %q must be an http(s) URL", i, w.URL))
+		}
+		if w.Secret == "" {
+			errs = append(errs, fmt.Errorf("notifiers.webhooks[%d].secret is required", i))
+		}
+		errs = append(errs, validateEvents(fmt.Sprintf("notifiers.webhooks[%d]", i), w.Events)...)
+	}
+	if c.Notifiers.DigestSchedule != "" {
+		if _, err := cron.ParseStandard(c.Notifiers.DigestSchedule); err != nil {
+			errs = append(errs, fmt.Errorf("notifiers.digest_schedule: %w", err))
+		}
+	}
+	if c.Notifiers.WeeklySchedule != "" {
+		if _, err := cron.ParseStandard(c.Notifiers.WeeklySchedule); err != nil {
+			errs = append(errs, fmt.Errorf("notifiers.weekly_schedule: %w", err))
+		}
+	}
+	chats := []struct {
+		kind string
+		list []ChatNotifierConfig
+	}{{"discord", c.Notifiers.Discord}, {"slack", c.Notifiers.Slack}}
+	for _, chat := range chats {
+		for i, n := range chat.list {
+			field := fmt.Sprintf("notifiers.%s[%d]", chat.kind, i)
+			if u, err := url.Parse(n.URL); err != nil || u.Scheme != "https" || u.Host == "" {
+				errs = append(errs, fmt.Errorf("%s.url %q must be an https URL", field, n.URL))
+			}
+			if n.Language != "" && n.Language != "vi" && n.Language != "en" {
+				errs = append(errs, fmt.Errorf("%s.language %q must be vi or en", field, n.Language))
+			}
+			if n.Unit != "" && !bottelegram.ValidUnit(n.Unit) {
+				errs = append(errs, fmt.Errorf("%s.unit %q must be luong or chi", field, n.Unit))
+			}
+			errs = append(errs, validateEvents(field, n.Events)...)
+		}
+	}
+	for i, e := range c.Notifiers.Email {
+		field := fmt.Sprintf("notifiers.email[%d]", i)
+		if e.Host == "" {
+			errs = append(errs, fmt.Errorf("%s.host is required", field))
+		}
+		if e.Port < 0 || e.Port > 65535 {
+			errs = append(errs, fmt.Errorf("%s.port %d is out of range", field, e.Port))
+		}
+		if _, err := mail.ParseAddress(e.From); err != nil {
+			errs = append(errs, fmt.Errorf("%s.from %q: %w", field, e.From, err))
+		}
+		if len(e.To) == 0 {
+			errs = append(errs, fmt.Errorf("%s.to needs at least one recipient", field))
+		}
+		for _, to := range e.To {
+			if _, err := mail.ParseAddress(to); err != nil {
+				errs = append(errs, fmt.Errorf("%s.to %q: %w", field, to, err))
+			}
+		}
+		if e.Language != "" && e.Language != "vi" && e.Language != "en" {
+			errs = append(errs, fmt.Errorf("%s.language %q must be vi or en", field, e.Language))
+		}
+		if e.Unit != "" && !bottelegram.ValidUnit(e.Unit) {
+			errs = append(errs, fmt.Errorf("%s.unit %q must be luong or chi", field, e.Unit))
+		}
+		errs = append(errs, validateEvents(field, e.Events)...)
+	}
+
+	return errors.Join(errs...)
+}
+
+func validateEvents(field string, events []string) []error {
+	var errs []error
+	for _, ev := range events {
+		if !slices.Contains(eventTypes, ev) {
+			errs = append(errs, fmt.Errorf("%s.events: unknown event %q", field, ev))
+		}
+	}
+	return errs
+}
+
+func setString(dst *string, v string) {
+	if v != "" {
+		*dst = v
+	}
+}
+
+func splitList(v string) []string {
+	var items []string
+	for _, item := range strings.Split(v, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
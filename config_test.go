@@ -0,0 +1,185 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+)
+
+// clearConfigEnv hides any configuration in the environment running the
+// tests.
+func clearConfigEnv(t *testing.T) {
+	for _, name := range []string{
+		"CONFIG_FILE", "HTTP_ADDR", "STORE_BACKEND", "STORE_PATH", "REDIS_ADDR", "REDIS_PASSWORD", "REDIS_DB",
+		"CRAWL_SCHEDULE", "CRAWL_CONCURRENCY", "CRAWL_TIMEOUT", "CACHE_STALE_AFTER", "GOLD_TYPES",
+		"TELEGRAM_SCHEDULE", "TELEGRAM_CONFIG",
+	} {
+		t.Setenv(name, "")
+	}
+}
+
+func writeConfigFile(t *testing.T, name, content string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), name)
+	if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestLoadConfigPrecedence(t *testing.T) {
+	file := writeConfigFile(t, "config.yaml", `
+http:
+  addr: ":9000"
+store:
+  backend: file
+  path: from-file.json
+  redis:
+    addr: file-redis:6379
+crawl:
+  concurrency: 2
+  timeout: 5s
+  gold_types: [sjc, doji_hn]
+`)
+
+	tests := []struct {
+		name  string
+		env   map[string]string
+		args  []string
+		check func(t *testing.T, cfg *Config)
+	}{
+		{
+			name: "defaults",
+			check: func(t *testing.T, cfg *Config) {
+				if cfg.HTTP.Addr != ":8080" || cfg.Store.Backend != "redis" || cfg.Crawl.Concurrency != 4 || cfg.Crawl.Timeout.Duration != 20*time.Second {
+					t.Errorf("defaults = %+v", cfg)
+				}
+				if !slices.Equal(cfg.Crawl.GoldTypes, GOLDTYPES) {
+					t.Errorf("gold types = %v, want every known type", cfg.Crawl.GoldTypes)
+				}
+			},
+		},
+		{
+			name: "file over defaults",
+			args: []string{"-config", file},
+			check: func(t *testing.T, cfg *Config) {
+				if cfg.HTTP.Addr != ":9000" || cfg.Store.Backend != "file" || cfg.Store.Path != "from-file.json" {
+					t.Errorf("file settings not applied: %+v", cfg)
+				}
+				if cfg.Crawl.Concurrency != 2 || cfg.Crawl.Timeout.Duration != 5*time.Second || !slices.Equal(cfg.Crawl.GoldTypes, []string{"sjc", "doji_hn"}) {
+					t.Errorf("crawl = %+v", cfg.Crawl)
+				}
+				if cfg.Crawl.Schedule != "0 */6 * * *" {
+					t.Errorf("schedule %q, want the default kept", cfg.Crawl.Schedule)
+				}
+			},
+		},
+		{
+			name: "file named by the environment",
+			env:  map[string]string{"CONFIG_FILE": file},
+			check: func(t *testing.T, cfg *Config) {
+				if cfg.HTTP.Addr != ":9000" {
+					t.Errorf("http.addr %q, want the file's", cfg.HTTP.Addr)
+				}
+			},
+		},
+		{
+			name: "environment over file",
+			env: map[string]string{
+				"STORE_PATH":        "from-env.json",
+				"CRAWL_CONCURRENCY": "8",
+				"CRAWL_TIMEOUT":     "30s",
+				"GOLD_TYPES":        "pnj_hn, sjc",
+			},
+			args: []string{"-config", file},
+			check: func(t *testing.T, cfg *Config) {
+				if cfg.Store.Path != "from-env.json" || cfg.Crawl.Concurrency != 8 || cfg.Crawl.Timeout.Duration != 30*time.Second {
+					t.Errorf("environment not applied: store %+v, crawl %+v", cfg.Store, cfg.Crawl)
+				}
+				if !slices.Equal(cfg.Crawl.GoldTypes, []string{"pnj_hn", "sjc"}) {
+					t.Errorf("gold types = %v", cfg.Crawl.GoldTypes)
+				}
+				if cfg.HTTP.Addr != ":9000" {
+					t.Errorf("http.addr %q, want the file's kept", cfg.HTTP.Addr)
+				}
+			},
+		},
+		{
+			name: "flags over environment",
+			env:  map[string]string{"STORE_PATH": "from-env.json", "HTTP_ADDR": ":7000"},
+			args: []string{"-config", file, "-store-path", "from-flag.json", "-gold-types", "sjc"},
+			check: func(t *testing.T, cfg *Config) {
+				if cfg.Store.Path != "from-flag.json" || !slices.Equal(cfg.Crawl.GoldTypes, []string{"sjc"}) {
+					t.Errorf("flags not applied: store %+v, gold types %v", cfg.Store, cfg.Crawl.GoldTypes)
+				}
+				if cfg.HTTP.Addr != ":7000" {
+					t.Errorf("http.addr %q, want the environment's kept", cfg.HTTP.Addr)
+				}
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			clearConfigEnv(t)
+			for k, v := range tt.env {
+				t.Setenv(k, v)
+			}
+			cfg, err := loadConfig(tt.args)
+			if err != nil {
+				t.Fatal(err)
+			}
+			tt.check(t, cfg)
+		})
+	}
+}
+
+func TestLoadConfigJSON(t *testing.T) {
+	clearConfigEnv(t)
+	file := writeConfigFile(t, "config.json", `{"store": {"backend": "memory"}, "crawl": {"stale_after": "1h"}}`)
+
+	cfg, err := loadConfig([]string{"-config", file})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cfg.Store.Backend != "memory" || cfg.Crawl.StaleAfter.Duration != time.Hour {
+		t.Errorf("JSON file not applied: store %+v, crawl %+v", cfg.Store, cfg.Crawl)
+	}
+}
+
+func TestLoadConfigErrors(t *testing.T) {
+	toml := writeConfigFile(t, "config.toml", "[http]\naddr = \":9000\"\n")
+	tests := []struct {
+		name    string
+		env     map[string]string
+		args    []string
+		wantErr string
+	}{
+		{name: "unknown backend", args: []string{"-store", "mongo"}, wantErr: `store.backend "mongo"`},
+		{name: "bad schedule", args: []string{"-crawl-schedule", "every minute"}, wantErr: "crawl.schedule"},
+		{name: "unknown gold type", args: []string{"-gold-types", "sjc,vang_gia"}, wantErr: `unknown gold type "vang_gia"`},
+		{name: "bad number", env: map[string]string{"CRAWL_CONCURRENCY": "four"}, wantErr: "CRAWL_CONCURRENCY"},
+		{name: "bad duration", env: map[string]string{"CRAWL_TIMEOUT": "20"}, wantErr: "CRAWL_TIMEOUT"},
+		{name: "zero concurrency", env: map[string]string{"CRAWL_CONCURRENCY": "0"}, wantErr: "crawl.concurrency"},
+		{name: "missing file", args: []string{"-config", "does-not-exist.yaml"}, wantErr: "does-not-exist.yaml"},
+		{name: "unsupported file", args: []string{"-config", toml}, wantErr: "unsupported config file type"},
+		{name: "unknown flag", args: []string{"-verbose"}, wantErr: "verbose"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			clearConfigEnv(t)
+			for k, v := range tt.env {
+				t.Setenv(k, v)
+			}
+			cfg, err := loadConfig(tt.args)
+			if err == nil {
+				t.Fatalf("loadConfig(%q) = %+v, want an error", tt.args, cfg)
+			}
+			if !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("error %q does not mention %q", err, tt.wantErr)
+			}
+		})
+	}
+}
@@ -0,0 +1,189 @@
+package main
+
+import (
+	"context"
+	"log"
+	"sync"
+	"time"
+)
+
+// CrawlResult is the outcome of crawling one gold type.
+type CrawlResult struct {
+	Type       string        `json:"type"`
+	OK         bool          `json:"ok"`
+	Duration   time.Duration `json:"-"`
+	DurationMS int64         `json:"duration_ms"`
+	Error      string        `json:"error,omitempty"`
+}
+
+// CrawlReport summarizes one crawl batch.
+type CrawlReport struct {
+	StartedAt  time.Time     `json:"started_at"`
+	DurationMS int64         `json:"duration_ms"`
+	Succeeded  int           `json:"succeeded"`
+	Failed     int           `json:"failed"`
+	Results    []CrawlResult `json:"results"`
+}
+
+// CrawlHook is called after a gold type was crawled and saved.
+type CrawlHook func(ctx context.Context, goldType string, goldPrice *GoldPrice)
+
+// BatchHook is called after a crawl batch finished.
+type BatchHook func(report *CrawlReport)
+
+// Crawler fetches gold types in parallel with a bounded number of workers
+// and a deadline per type.
+type Crawler struct {
+	concurrency int
+	timeout     time.Duration
+
+	flights flightGroup
+
+	mu         sync.RWMutex
+	last       *CrawlReport
+	hooks      []CrawlHook
+	batchHooks []BatchHook
+}
+
+func NewCrawler(concurrency int, timeout time.Duration) *Crawler {
+	if concurrency < 1 {
+		concurrency = 1
+	}
+	return &Crawler{concurrency: concurrency, timeout: timeout}
+}
+
+// Run crawls and saves every gold type, returning once all have finished or
+// ctx is done. Results keep the order of goldTypes.
+func (c *Crawler) Run(ctx context.Context, goldTypes []string) *CrawlReport {
+	report := &CrawlReport{
+		StartedAt: time.Now(),
+		Results:   make([]CrawlResult, len(goldTypes)),
+	}
+
+	sem := make(chan struct{}, c.concurrency)
+	var wg sync.WaitGroup
+	for i, goldType := range goldTypes {
+		wg.Add(1)
+		go func(i int, goldType string) {
+			defer wg.Done()
+
+			res := CrawlResult{Type: goldType}
+			select {
+			case sem <- struct{}{}:
+				defer func() { <-sem }()
+				res = c.crawlOne(ctx, goldType)
+			case <-ctx.Done():
+				res.Error = ctx.Err().Error()
+			}
+			report.Results[i] = res
+		}(i, goldType)
+	}
+	wg.Wait()
+
+	report.DurationMS = time.Since(report.StartedAt).Milliseconds()
+	for _, res := range report.Results {
+		if res.OK {
+			report.Succeeded++
+		} else {
+			report.Failed++
+		}
+	}
+
+	c.mu.Lock()
+	c.last = report
+	batchHooks := c.batchHooks
+	c.mu.Unlock()
+
+	for _, hook := range batchHooks {
+		hook(report)
+	}
+	return report
+}
+
+// Refresh crawls and saves one gold type. Concurrent refreshes of the same
+// type share a single upstream fetch, which runs under its own deadline so
+// that one caller going away does not abort it for the others.
+func (c *Crawler) Refresh(ctx context.Context, goldType string) error {
+	return c.flights.Do(ctx, goldType, func() error {
+		crawlCtx := context.WithoutCancel(ctx)
+		if c.timeout > 0 {
+			var cancel context.CancelFunc
+			crawlCtx, cancel = context.WithTimeout(crawlCtx, c.timeout)
+			defer cancel()
+		}
+		goldPrice, err := crawlAndSaveGoldPrice(crawlCtx, goldType)
+		if err != nil {
+			return err
+		}
+		c.runHooks(crawlCtx, goldType, goldPrice)
+		return nil
+	})
+}
+
+// OnCrawled registers hook to run after every successful crawl, in the
+// crawling goroutine.
+func (c *Crawler) OnCrawled(hook CrawlHook) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.hooks = append(c.hooks, hook)
+}
+
+// OnBatch registers hook to run after every Run.
+func (c *Crawler) OnBatch(hook BatchHook) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.batchHooks = append(c.batchHooks, hook)
+}
+
+func (c *Crawler) runHooks(ctx context.Context, goldType string, goldPrice *GoldPrice) {
+	c.mu.RLock()
+	hooks := c.hooks
+	c.mu.RUnlock()
+
+	for _, hook := range hooks {
+		hook(ctx, goldType, goldPrice)
+	}
+}
+
+// RefreshInBackground starts a Refresh, joining one that is already running.
+func (c *Crawler) RefreshInBackground(goldType string) {
+	go func() {
+		if err := c.Refresh(context.Background(), goldType); err != nil {
+			log.Printf("Background refresh of %s failed: %v", goldType, err)
+		}
+	}()
+}
+
+func (c *Crawler) crawlOne(ctx context.Context, goldType string) CrawlResult {
+	start := time.Now()
+	err := c.Refresh(ctx, goldType)
+	res := CrawlResult{
+		Type:       goldType,
+		OK:         err == nil,
+		Duration:   time.Since(start),
+		DurationMS: time.Since(start).Milliseconds(),
+	}
+	if err != nil {
+		res.Error = err.Error()
+	}
+	return res
+}
+
+// LastReport returns the most recent report, or nil before the first run.
+func (c *Crawler) LastReport() *CrawlReport {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.last
+}
+
+// logCrawlReport writes one line per gold type plus a summary.
+func logCrawlReport(name string, report *CrawlReport) {
+	for _, res := range report.Results {
+		if res.OK {
+			log.Printf("[%s] %s crawled in %v", name, res.Type, res.Duration.Round(time.Millisecond))
+		} else {
+			log.Printf("[%s] %s failed after %v: %s", name, res.Type, res.Duration.Round(time.Millisecond), res.Error)
+		}
+	}
+	log.Printf("[%s] %d succeeded, %d failed in %dms", name, report.Succeeded, report.Failed, report.DurationMS)
+}
@@ -0,0 +1,184 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"pricegoldtoday/vntime"
+)
+
+// fakeSource serves every gold type it lists from fetch, which defaults to
+// a one-day chart of today.
+type fakeSource struct {
+	name      string
+	goldTypes []string
+	fetch     func(ctx context.Context, goldType string) (*GoldPrice, error)
+	calls     atomic.Int32
+}
+
+func (s *fakeSource) Name() string        { return s.name }
+func (s *fakeSource) GoldTypes() []string { return s.goldTypes }
+
+func (s *fakeSource) Fetch(ctx context.Context, goldType string) (*GoldPrice, error) {
+	s.calls.Add(1)
+	if s.fetch != nil {
+		return s.fetch(ctx, goldType)
+	}
+	return todaysPrice(goldType), nil
+}
+
+func todaysPrice(goldType string) *GoldPrice {
+	now := vntime.Now()
+	return &GoldPrice{
+		Type:       goldType,
+		Dates:      []string{vntime.Category(now)},
+		BuyPrices:  []float64{118e6},
+		SellPrices: []float64{120e6},
+		UpdatedAt:  now,
+	}
+}
+
+// useTestBackends swaps the store and the sources for an empty memory store
+// and srcs until the test ends.
+func useTestBackends(t *testing.T, srcs ...Source) {
+	t.Helper()
+	oldStore, oldSources := store, sources
+	store, sources = newMemoryStore(), NewSourceRegistry(srcs...)
+	t.Cleanup(func() { store, sources = oldStore, oldSources })
+}
+
+// waitForFlights blocks until none of c's refreshes is in flight.
+func waitForFlights(c *Crawler) {
+	for {
+		c.flights.mu.Lock()
+		n := len(c.flights.calls)
+		c.flights.mu.Unlock()
+		if n == 0 {
+			return
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+func TestCrawlerRunBoundsConcurrency(t *testing.T) {
+	var inFlight, peak atomic.Int32
+	src := &fakeSource{name: "fake", goldTypes: GOLDTYPES}
+	src.fetch = func(ctx context.Context, goldType string) (*GoldPrice, error) {
+		n := inFlight.Add(1)
+		defer inFlight.Add(-1)
+		for {
+			p := peak.Load()
+			if n <= p || peak.CompareAndSwap(p, n) {
+				break
+			}
+		}
+		time.Sleep(20 * time.Millisecond)
+		return todaysPrice(goldType), nil
+	}
+	useTestBackends(t, src)
+
+	c := NewCrawler(2, time.Second)
+	report := c.Run(context.Background(), GOLDTYPES)
+
+	if report.Succeeded != len(GOLDTYPES) || report.Failed != 0 {
+		t.Fatalf("succeeded %d, failed %d: %+v", report.Succeeded, report.Failed, report.Results)
+	}
+	if p := peak.Load(); p > 2 {
+		t.Errorf("%d fetches ran at once, want at most 2", p)
+	}
+	for i, res := range report.Results {
+		if res.Type != GOLDTYPES[i] {
+			t.Errorf("result %d is %s, want %s", i, res.Type, GOLDTYPES[i])
+		}
+		if _, err := store.GetGoldPrice(context.Background(), res.Type); err != nil {
+			t.Errorf("%s was not saved: %v", res.Type, err)
+		}
+	}
+	if c.LastReport() != report {
+		t.Error("LastReport does not return the last run")
+	}
+}
+
+func TestCrawlerRunTimesOutPerType(t *testing.T) {
+	src := &fakeSource{name: "fake", goldTypes: []string{"sjc", "doji_hn"}}
+	src.fetch = func(ctx context.Context, goldType string) (*GoldPrice, error) {
+		if goldType == "sjc" {
+			<-ctx.Done() // hangs until the crawl gives up
+			return nil, ctx.Err()
+		}
+		return todaysPrice(goldType), nil
+	}
+	useTestBackends(t, src)
+
+	start := time.Now()
+	report := NewCrawler(2, 50*time.Millisecond).Run(context.Background(), []string{"sjc", "doji_hn"})
+
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Errorf("Run took %v, the hanging type was not cut off", elapsed)
+	}
+	sjc, doji := report.Results[0], report.Results[1]
+	if sjc.OK || !strings.Contains(sjc.Error, context.DeadlineExceeded.Error()) {
+		t.Errorf("sjc = %+v, want a deadline error", sjc)
+	}
+	if !doji.OK {
+		t.Errorf("doji_hn = %+v, want it crawled despite sjc hanging", doji)
+	}
+	if report.Succeeded != 1 || report.Failed != 1 {
+		t.Errorf("succeeded %d, failed %d; want 1, 1", report.Succeeded, report.Failed)
+	}
+}
+
+func TestCrawlerRunStopsWithContext(t *testing.T) {
+	release := make(chan struct{})
+	src := &fakeSource{name: "fake", goldTypes: GOLDTYPES}
+	src.fetch = func(ctx context.Context, goldType string) (*GoldPrice, error) {
+		select {
+		case <-release:
+			return nil, errors.New("released")
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+	}
+	useTestBackends(t, src)
+
+	// The detached fetch outlives Run; let it finish before the backends
+	// are restored.
+	c := NewCrawler(1, time.Minute)
+	t.Cleanup(func() {
+		close(release)
+		waitForFlights(c)
+	})
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	report := c.Run(ctx, GOLDTYPES)
+
+	if report.Succeeded != 0 || report.Failed != len(GOLDTYPES) {
+		t.Errorf("succeeded %d, failed %d; want every type to fail", report.Succeeded, report.Failed)
+	}
+	for _, res := range report.Results {
+		if res.Error == "" {
+			t.Errorf("%s has no error", res.Type)
+		}
+	}
+}
+
+func TestCrawlerRunReportsSourceErrors(t *testing.T) {
+	src := &fakeSource{name: "fake", goldTypes: []string{"sjc"}}
+	src.fetch = func(ctx context.Context, goldType string) (*GoldPrice, error) {
+		return nil, errors.New("no prices found")
+	}
+	useTestBackends(t, src)
+
+	report := NewCrawler(1, time.Second).Run(context.Background(), []string{"sjc", "doji_hn"})
+	if res := report.Results[0]; res.OK || !strings.Contains(res.Error, "no prices found") {
+		t.Errorf("sjc = %+v, want the source error", res)
+	}
+	if res := report.Results[1]; res.OK || !strings.Contains(res.Error, errNoSource.Error()) {
+		t.Errorf("doji_hn = %+v, want no source", res)
+	}
+}
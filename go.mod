@@ -13,7 +13,13 @@ require (
 	github.com/PuerkitoBio/goquery v1.10.3
 	github.com/cespare/xxhash/v2 v2.1.2 // indirect
 	github.com/dgryski/go-rendezvous v0.0.0-20200823014737-9f7001d12a5f // indirect
-	golang.org/x/sys v0.32.0 // indirect
+)
+
+require (
+	github.com/gorilla/websocket v1.5.3
+	golang.org/x/image v0.26.0
+	golang.org/x/text v0.24.0
+	gopkg.in/yaml.v3 v3.0.1
 )
 
 require github.com/andybalholm/cascadia v1.3.3 // indirect
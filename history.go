@@ -0,0 +1,48 @@
+package main
+
+import (
+	"log"
+
+	"pricegoldtoday/vntime"
+)
+
+const historyDateLayout = vntime.DateLayout
+
+// PricePoint is one day of the persistent per-type price history.
+type PricePoint struct {
+	Date   string  `json:"date"` // YYYY-MM-DD
+	Buy    float64 `json:"buy"`
+	Sell   float64 `json:"sell"`
+	Source string  `json:"source,omitempty"` // the source the prices came from
+}
+
+// samePrice reports whether p and q are the same day at the same prices,
+// whichever source they came from.
+func (p PricePoint) samePrice(q PricePoint) bool {
+	return p.Date == q.Date && p.Buy == q.Buy && p.Sell == q.Sell
+}
+
+// historyFromGoldPrice converts a crawled chart snapshot into dated points.
+// Points with a missing price are dropped so they never overwrite good data.
+func historyFromGoldPrice(goldPrice *GoldPrice) []PricePoint {
+	dates, err := vntime.ResolveCategories(goldPrice.Dates, goldPrice.UpdatedAt)
+	if err != nil {
+		log.Printf("Cannot resolve chart dates for %s: %v", goldPrice.Type, err)
+		return nil
+	}
+	n := min(len(dates), len(goldPrice.BuyPrices), len(goldPrice.SellPrices))
+
+	var points []PricePoint
+	for i := 0; i < n; i++ {
+		if goldPrice.BuyPrices[i] == 0 || goldPrice.SellPrices[i] == 0 {
+			continue
+		}
+		points = append(points, PricePoint{
+			Date:   dates[i].Format(historyDateLayout),
+			Buy:    goldPrice.BuyPrices[i],
+			Sell:   goldPrice.SellPrices[i],
+			Source: goldPrice.Source,
+		})
+	}
+	return points
+}
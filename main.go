@@ -3,21 +3,18 @@ package main
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
-	"io"
 	"log"
 	"net/http"
 	"os"
 	"os/signal"
-	"regexp"
-	"strings"
 	"syscall"
 	"time"
 
 	bottelegram "pricegoldtoday/bot"
+	"pricegoldtoday/vntime"
 
-	"github.com/PuerkitoBio/goquery"
-	"github.com/go-redis/redis/v8"
 	"github.com/gorilla/mux"
 	"github.com/robfig/cron/v3"
 )
@@ -28,23 +25,46 @@ type GoldPrice struct {
 	BuyPrices  []float64 `json:"buy_prices"`
 	SellPrices []float64 `json:"sell_prices"`
 	UpdatedAt  time.Time `json:"updated_at"`
+	Source     string    `json:"source,omitempty"`
 }
 
 var (
-	rdb *redis.Client
-	ctx = context.Background()
+	store     Store
+	crawler   *Crawler
+	notifiers *NotifierHub
+	stream    *streamHub
+	sockets   *wsHub
+	ctx       = context.Background()
+
+	// staleAfter is the age after which handlers refresh a snapshot in the
+	// background while still serving it.
+	staleAfter time.Duration
 )
 
-const (
-	redisKeyPrefix  = "gold_price:"
-	defaultGoldType = "doji_hn"
-)
+const defaultGoldType = "doji_hn"
 
+// GOLDTYPES lists the gold types served and crawled; crawl.gold_types in the
+// config narrows it at startup.
 var GOLDTYPES = []string{"sjc", "doji_hn", "doji_sg", "bao_tin_minh_chau", "phu_quy_sjc", "pnj_tp_hcml", "pnj_hn"} // example gold types
 
+// sources lists the upstreams crawled for each gold type, in failover order.
+var sources = NewSourceRegistry(newTwentyFourHSource(GOLDTYPES))
+
 func main() {
-	// Initialize Redis
-	initRedis()
+	if len(os.Args) > 1 && os.Args[1] == "webhook" {
+		runWebhookCommand(os.Args[2:])
+		return
+	}
+
+	cfg, err := loadConfig(os.Args[1:])
+	if err != nil {
+		log.Fatalf("Failed to load configuration: %v", err)
+	}
+
+	// Initialize storage
+	initStore(cfg.Store)
+	initCrawler(cfg.Crawl)
+	initNotifiers(cfg.Notifiers)
 
 	// Initial crawl when server starts
 	if true {
@@ -52,9 +72,32 @@ func main() {
 	}
 
 	// Start cron job for crawling gold prices
-	cronStopper := startCronJob()
-
-	cronStopperTelegram := telegramCronJob()
+	cronStopper := startCronJob(cfg.Crawl.Schedule)
+	cronStopperNotifiers := notifierCronJob(cfg.Notifiers)
+
+	var cronStopperTelegram *cron.Cron
+	var telegramWebhook http.Handler
+	botCtx, stopBot := context.WithCancel(ctx)
+	if bot := initTelegram(cfg.Telegram); bot != nil {
+		cronStopperTelegram = telegramCronJob(cfg.Telegram, bot)
+
+		alerts := newAlertEvaluator(bot, cfg.Alerts, cfg.Telegram)
+		crawler.OnCrawled(alerts.Evaluate)
+
+		router := newCommandRouter(cfg.Telegram, bot, alerts)
+		switch cfg.Telegram.Mode {
+		case "polling":
+			go func() {
+				resolveBotUsername(botCtx, bot, router)
+				bot.Poll(botCtx, router)
+			}()
+			log.Println("Telegram command polling started")
+		case "webhook":
+			go resolveBotUsername(botCtx, bot, router)
+			telegramWebhook = bot.WebhookHandler(router, cfg.Telegram.Webhook.Secret)
+			log.Printf("Telegram webhook served on %s", cfg.Telegram.Webhook.Path)
+		}
+	}
 	// Ensure cron jobs are stopped on exit
 	defer func() {
 		if cronStopper != nil {
@@ -65,6 +108,10 @@ func main() {
 			cronStopperTelegram.Stop()
 			log.Println("Telegram cron job stopped")
 		}
+		if cronStopperNotifiers != nil {
+			cronStopperNotifiers.Stop()
+			log.Println("Notifier cron job stopped")
+		}
 	}()
 
 	// Create channel for graceful shutdown
@@ -72,11 +119,12 @@ func main() {
 	signal.Notify(done, os.Interrupt, syscall.SIGINT, syscall.SIGTERM)
 
 	// Start HTTP server in a separate goroutine
-	httpServer := startHTTPServer()
+	httpServer := startHTTPServer(cfg.HTTP.Addr, cfg.Telegram.Webhook.Path, telegramWebhook)
 
 	// Wait for shutdown signal
 	<-done
 	log.Println("Received shutdown signal, initiating graceful shutdown...")
+	stopBot()
 
 	// // Shutdown cron job
 	// if cronStopper != nil {
@@ -84,7 +132,9 @@ func main() {
 	// 	log.Println("Cron job stopped")
 	// }
 
-	// Shutdown HTTP server
+	// Shutdown HTTP server, ending the open event streams and sockets first
+	stream.Close()
+	sockets.Close()
 	if httpServer != nil {
 		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
 		defer cancel()
@@ -94,12 +144,12 @@ func main() {
 		log.Println("HTTP server stopped")
 	}
 
-	// Close Redis connection
-	if rdb != nil {
-		if err := rdb.Close(); err != nil {
-			log.Printf("Redis connection close error: %v", err)
+	// Close storage
+	if store != nil {
+		if err := store.Close(); err != nil {
+			log.Printf("Store close error: %v", err)
 		}
-		log.Println("Redis connection closed")
+		log.Println("Store closed")
 	}
 
 	log.Println("Application shutdown complete")
@@ -107,112 +157,186 @@ func main() {
 
 func initialCrawl() {
 	log.Println("Performing initial gold price crawl...")
-	for _, goldType := range GOLDTYPES {
-		if err := crawlAndSaveGoldPrice(goldType); err != nil {
-			log.Printf("Initial crawl failed for %s: %v", goldType, err)
-			continue
+	logCrawlReport("initial crawl", crawler.Run(ctx, GOLDTYPES))
+}
+
+func initCrawler(cfg CrawlConfig) {
+	GOLDTYPES = cfg.GoldTypes
+	staleAfter = cfg.StaleAfter.Duration
+	crawler = NewCrawler(cfg.Concurrency, cfg.Timeout.Duration)
+}
+
+func initNotifiers(cfg NotifiersConfig) {
+	stream = newStreamHub()
+	sockets = newWSHub()
+	list := []Notifier{stream, sockets}
+	for i, w := range cfg.Webhooks {
+		if w.Name == "" {
+			w.Name = fmt.Sprintf("webhook-%d", i+1)
+		}
+		if w.Timeout.Duration <= 0 {
+			w.Timeout.Duration = 10 * time.Second
+		}
+		list = append(list, newWebhookNotifier(w))
+		log.Printf("Webhook notifier %s enabled", w.Name)
+	}
+	for i, d := range cfg.Discord {
+		d = chatNotifierDefaults(d, fmt.Sprintf("discord-%d", i+1))
+		list = append(list, newDiscordNotifier(d))
+		log.Printf("Discord notifier %s enabled", d.Name)
+	}
+	for i, sl := range cfg.Slack {
+		sl = chatNotifierDefaults(sl, fmt.Sprintf("slack-%d", i+1))
+		list = append(list, newSlackNotifier(sl))
+		log.Printf("Slack notifier %s enabled", sl.Name)
+	}
+	for i, e := range cfg.Email {
+		if e.Name == "" {
+			e.Name = fmt.Sprintf("email-%d", i+1)
 		}
-		log.Printf("Successfully crawled initial data for %s", goldType)
+		if e.Port == 0 {
+			e.Port = 587
+		}
+		if e.Language == "" {
+			e.Language = bottelegram.DefaultLocale
+		}
+		if e.Unit == "" {
+			e.Unit = bottelegram.UnitLuong
+		}
+		n, err := newEmailNotifier(e)
+		if err != nil {
+			log.Fatalf("Email notifier %s: %v", e.Name, err)
+		}
+		list = append(list, n)
+		log.Printf("Email notifier %s enabled for %d recipients", e.Name, len(e.To))
 	}
+
+	notifiers = NewNotifierHub(list, cfg.Timeout.Duration, cfg.DeadLetter)
+	crawler.OnCrawled(notifiers.PriceHook)
+	crawler.OnBatch(notifiers.PublishCrawl)
+	sources.OnDown(notifiers.PublishSourceDown)
 }
 
-func initRedis() {
-	rdb = redis.NewClient(&redis.Options{
-		Addr:     "localhost:6379", // or your Redis address
-		Password: "",               // no password set
-		DB:       0,                // use default DB
-	})
+func chatNotifierDefaults(cfg ChatNotifierConfig, name string) ChatNotifierConfig {
+	if cfg.Name == "" {
+		cfg.Name = name
+	}
+	if cfg.Language == "" {
+		cfg.Language = bottelegram.DefaultLocale
+	}
+	if cfg.Unit == "" {
+		cfg.Unit = bottelegram.UnitLuong
+	}
+	if cfg.Timeout.Duration <= 0 {
+		cfg.Timeout.Duration = 10 * time.Second
+	}
+	return cfg
+}
+
+// notifierCronJob publishes digest.daily and digest.weekly on their
+// schedules. It returns nil when there is no schedule or no notifier to
+// deliver to.
+func notifierCronJob(cfg NotifiersConfig) *cron.Cron {
+	if (cfg.DigestSchedule == "" && cfg.WeeklySchedule == "") || len(notifiers.notifiers) == 0 {
+		return nil
+	}
+	c := cron.New()
+
+	for eventType, schedule := range map[string]string{eventDigestDaily: cfg.DigestSchedule, eventDigestWeekly: cfg.WeeklySchedule} {
+		if schedule == "" {
+			continue
+		}
+		_, err := c.AddFunc(schedule, func() {
+			notifiers.PublishDigest(ctx, eventType)
+		})
+		if err != nil {
+			log.Fatalf("Error setting up cron job: %v", err)
+		}
+		log.Printf("Notifier %s cron job started with schedule %q", eventType, schedule)
+	}
+
+	c.Start()
 
-	// Test Redis connection
-	_, err := rdb.Ping(ctx).Result()
+	return c
+}
+
+func initStore(cfg StoreConfig) {
+	var err error
+	store, err = openStore(cfg)
 	if err != nil {
-		log.Fatalf("Failed to connect to Redis: %v", err)
+		log.Fatalf("Failed to open %q store: %v", cfg.Backend, err)
 	}
-	log.Println("Connected to Redis")
+	log.Printf("Using %q store", cfg.Backend)
 }
 
-func startCronJob() *cron.Cron {
+func startCronJob(schedule string) *cron.Cron {
 	c := cron.New()
 
-	// Run every 6 hours
-	_, err := c.AddFunc("0 */6 * * *", func() {
+	_, err := c.AddFunc(schedule, func() {
 		log.Println("Running scheduled gold price crawl job...")
-		for _, goldType := range GOLDTYPES {
-			if err := crawlAndSaveGoldPrice(goldType); err != nil {
-				log.Printf("Error crawling gold price for %s: %v", goldType, err)
-			} else {
-				log.Printf("Successfully updated gold price for %s", goldType)
-			}
-		}
+		logCrawlReport("scheduled crawl", crawler.Run(ctx, GOLDTYPES))
 	})
 	if err != nil {
 		log.Fatalf("Error setting up cron job: %v", err)
 	}
 
 	c.Start()
-	log.Println("Cron job started to run every 6 hours")
+	log.Printf("Cron job started with schedule %q", schedule)
 
 	return c
 }
-func telegramCronJob() *cron.Cron {
-	c := cron.New()
 
-	// Run every 7 hours
-	// _, err := c.AddFunc("0 7 * * *", func() {
-	_, err := c.AddFunc("@every 1m", func() {
-		dataGold := &bottelegram.GoldPriceResponse{}
-		for _, goldType := range GOLDTYPES {
-			goldPrice, err := getGoldPriceFromRedis(goldType)
-			if err != nil {
-				// Nếu không có trong Redis, thử crawl mới
-				if err := crawlAndSaveGoldPrice(goldType); err != nil {
-					log.Printf("Failed to crawl gold price for %s: %v", goldType, err)
-					continue
-				}
-				// Thử lấy lại từ Redis sau khi crawl
-				goldPrice, err = getGoldPriceFromRedis(goldType)
-				if err != nil {
-					log.Printf("Still cannot get gold price for %s: %v", goldType, err)
-					continue
-				}
-			}
-			data := bottelegram.GoldPriceData{
-				Type:       goldType,
-				Dates:      goldPrice.Dates,
-				BuyPrices:  goldPrice.BuyPrices,
-				SellPrices: goldPrice.SellPrices,
-				UpdatedAt:  goldPrice.UpdatedAt.Format(time.RFC3339),
-			}
-			if goldType == "doji_hn" {
-				dataGold.DojiHN = data
-			} else if goldType == "doji_sg" {
-				dataGold.DojiSG = data
-			} else if goldType == "pnj_tp_hcml" {
-				dataGold.PNJTPHCML = data
-			} else if goldType == "pnj_hn" {
-				dataGold.PNJHN = data
-			} else if goldType == "bao_tin_minh_chau" {
-				dataGold.BaoTinMinhChau = data
-			} else if goldType == "phu_quy_sjc" {
-				dataGold.PhuQuySJC = data
-			} else if goldType == "sjc" {
-				dataGold.SJC = data
-			}
+// initTelegram builds the bot from the config, filling gaps from the legacy
+// bot config file. It returns nil when the bot is not configured and not
+// required.
+func initTelegram(cfg TelegramConfig) *bottelegram.Bot {
+	botConfig := bottelegram.Config{
+		TelegramBotToken: cfg.BotToken,
+		TelegramChatID:   cfg.ChatID,
+		APIURL:           cfg.APIURL,
+		TemplateDir:      cfg.TemplatesDir,
+	}
+	if cfg.ConfigFile != "" && (botConfig.TelegramBotToken == "" || botConfig.TelegramChatID == "") {
+		legacy, err := bottelegram.LoadConfigFile(cfg.ConfigFile)
+		switch {
+		case err == nil:
+			setString(&botConfig.TelegramBotToken, legacy.TelegramBotToken)
+			setString(&botConfig.TelegramChatID, legacy.TelegramChatID)
+		case !errors.Is(err, os.ErrNotExist):
+			log.Printf("Ignoring Telegram config file %s: %v", cfg.ConfigFile, err)
 		}
-		log.Println("Running scheduled gold price crawl job...")
-		err := bottelegram.SendGoldPriceNotification(dataGold)
-		if err != nil {
-			log.Printf("Error sending Telegram notification: %v", err)
-		} else {
-			log.Println("Successfully sent Telegram notification with gold prices")
+	}
+
+	bot, err := bottelegram.New(botConfig)
+	if err != nil {
+		if cfg.Required {
+			log.Fatalf("Telegram bot is required but misconfigured (%v): %v", botConfig, err)
 		}
+		log.Printf("Telegram notifications disabled: %v", err)
+		return nil
+	}
+	log.Printf("Telegram notifications enabled for %v", botConfig)
+	return bot
+}
+
+func telegramCronJob(cfg TelegramConfig, bot *bottelegram.Bot) *cron.Cron {
+	// A tick waiting out a 429 or uploading charts can outlast the next one;
+	// overlapping runs would read the same state and send digests twice.
+	c := cron.New(cron.WithChain(cron.SkipIfStillRunning(cron.DefaultLogger)))
+
+	seedDefaultSubscriber(bot, cfg)
+
+	// Check every minute which subscribers are due according to their own
+	// schedules.
+	_, err := c.AddFunc("@every 1m", func() {
+		notifySubscribers(ctx, bot, time.Now())
 	})
 	if err != nil {
 		log.Fatalf("Error setting up cron job: %v", err)
 	}
 
 	c.Start()
-	log.Println("Cron job started to run every 7 hours")
+	log.Printf("Telegram cron job started, default digest schedule %q", cfg.Schedule)
 
 	return c
 }
@@ -235,22 +359,50 @@ func withCORS(next http.Handler) http.Handler {
 	})
 }
 
-func startHTTPServer() *http.Server {
+// withKnownType rejects requests whose {type} is not an enabled gold type
+// before they can trigger an upstream crawl.
+func withKnownType(next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		goldType := mux.Vars(r)["type"]
+		if _, ok := enabledProvider(goldType); !ok {
+			respondWithError(w, http.StatusNotFound, fmt.Sprintf("Unknown gold type %q", goldType))
+			return
+		}
+		next(w, r)
+	}
+}
+
+// startHTTPServer serves the API, plus telegramWebhook at webhookPath when it
+// is not nil.
+func startHTTPServer(addr, webhookPath string, telegramWebhook http.Handler) *http.Server {
 	r := mux.NewRouter()
 	corsRouter := withCORS(r)
 
 	r.HandleFunc("/api/gold-price", getGoldPriceHandler).Methods("GET")
-	r.HandleFunc("/api/gold-price/{type}", getGoldPriceByTypeHandler).Methods("GET")
+	r.HandleFunc("/api/gold-price/{type}", withKnownType(getGoldPriceByTypeHandler)).Methods("GET")
+	r.HandleFunc("/api/gold-price/{type}/history", withKnownType(getGoldHistoryHandler)).Methods("GET")
+	r.HandleFunc("/api/providers", getProvidersHandler).Methods("GET")
+	r.HandleFunc("/api/chart.png", getChartHandler).Methods("GET")
+	r.HandleFunc("/api/crawl-report", getCrawlReportHandler).Methods("GET")
+	r.Handle("/api/stream", stream).Methods("GET")
+	r.Handle("/api/ws", sockets).Methods("GET")
 	r.HandleFunc("/health", healthCheckHandler).Methods("GET")
 
-	port := "8080"
+	v2 := r.PathPrefix("/api/v2").Subrouter()
+	v2.HandleFunc("/gold-price", getGoldPriceV2Handler).Methods("GET")
+	v2.HandleFunc("/gold-price/{type}", withKnownType(getGoldPriceByTypeV2Handler)).Methods("GET")
+
+	if telegramWebhook != nil {
+		r.Handle(webhookPath, telegramWebhook).Methods("POST")
+	}
+
 	srv := &http.Server{
-		Addr:    ":" + port,
+		Addr:    addr,
 		Handler: corsRouter,
 	}
 
 	go func() {
-		log.Printf("Starting HTTP server on port %s", port)
+		log.Printf("Starting HTTP server on %s", addr)
 		if err := srv.ListenAndServe(); err != nil && err != http.ErrServerClosed {
 			log.Fatalf("HTTP server error: %v", err)
 		}
@@ -259,33 +411,29 @@ func startHTTPServer() *http.Server {
 	return srv
 }
 func healthCheckHandler(w http.ResponseWriter, r *http.Request) {
-	respondWithJSON(w, http.StatusOK, map[string]string{"status": "ok"})
+	status := "ok"
+	sourceStatus := sources.Status()
+	for _, s := range sourceStatus {
+		if s.State != breakerClosed {
+			status = "degraded"
+		}
+	}
+
+	respondWithJSON(w, http.StatusOK, map[string]interface{}{
+		"status":  status,
+		"sources": sourceStatus,
+	})
+}
+
+func getProvidersHandler(w http.ResponseWriter, r *http.Request) {
+	respondWithJSON(w, http.StatusOK, map[string]interface{}{"providers": enabledProviders()})
 }
 
 func getGoldPriceHandler(w http.ResponseWriter, r *http.Request) {
 	// Danh sách các loại vàng cần lấy
 
-	// Tạo map để lưu kết quả
-	result := make(map[string]*GoldPrice)
-
-	// Duyệt qua từng loại vàng
-	for _, goldType := range GOLDTYPES {
-		goldPrice, err := getGoldPriceFromRedis(goldType)
-		if err != nil {
-			// Nếu không có trong Redis, thử crawl mới
-			if err := crawlAndSaveGoldPrice(goldType); err != nil {
-				log.Printf("Failed to crawl gold price for %s: %v", goldType, err)
-				continue
-			}
-			// Thử lấy lại từ Redis sau khi crawl
-			goldPrice, err = getGoldPriceFromRedis(goldType)
-			if err != nil {
-				log.Printf("Still cannot get gold price for %s: %v", goldType, err)
-				continue
-			}
-		}
-		result[goldType] = goldPrice
-	}
+	// Lấy giá của tất cả các loại vàng, crawl song song các loại còn thiếu
+	result := loadGoldPrices(r.Context(), GOLDTYPES)
 
 	// Nếu không có dữ liệu nào
 	if len(result) == 0 {
@@ -303,140 +451,153 @@ func getGoldPriceByTypeHandler(w http.ResponseWriter, r *http.Request) {
 }
 
 func getGoldPriceByType(w http.ResponseWriter, r *http.Request, goldType string) {
-	// Try to get from Redis first
-	goldPrice, err := getGoldPriceFromRedis(goldType)
-	if err == nil && goldPrice != nil {
-		respondWithJSON(w, http.StatusOK, goldPrice)
+	query, err := parseSeriesQuery(r.URL.Query(), vntime.Now())
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, err.Error())
 		return
 	}
 
-	// If not found in Redis, crawl new data
-	log.Printf("Gold price for %s not found in Redis, crawling new data...", goldType)
-	if err := crawlAndSaveGoldPrice(goldType); err != nil {
-		respondWithError(w, http.StatusInternalServerError, fmt.Sprintf("Failed to crawl gold price: %v", err))
+	goldPrice, err := loadGoldPrice(goldType)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, err.Error())
 		return
 	}
 
-	// Try to get again
-	goldPrice, err = getGoldPriceFromRedis(goldType)
-	if err != nil {
-		respondWithError(w, http.StatusInternalServerError, fmt.Sprintf("Failed to get gold price: %v", err))
-		return
+	// Slice from the history when a range was requested
+	if query.IsSet() {
+		query, err := query.v1Range(vntime.Now())
+		if err != nil {
+			respondWithError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+		points, err := store.History(ctx, goldType, query.From, query.To)
+		if err != nil {
+			respondWithError(w, http.StatusInternalServerError, fmt.Sprintf("Failed to load history: %v", err))
+			return
+		}
+		goldPrice = goldPriceFromHistory(goldPrice, query.Apply(points))
 	}
 
 	respondWithJSON(w, http.StatusOK, goldPrice)
 }
 
-func crawlAndSaveGoldPrice(goldType string) error {
-	// Crawl data from website
-	goldPrice, err := crawlGoldPrice(goldType)
-	if err != nil {
-		return fmt.Errorf("crawl failed: %w", err)
+// loadGoldPrice returns the stored snapshot, crawling it first on a miss.
+// A stale snapshot is served as-is while a refresh runs in the background.
+func loadGoldPrice(goldType string) (*GoldPrice, error) {
+	// Try to get from the store first
+	goldPrice, err := store.GetGoldPrice(ctx, goldType)
+	if err == nil && goldPrice != nil {
+		revalidateIfStale(goldPrice)
+		return goldPrice, nil
 	}
 
-	// Save to Redis
-	if err := saveGoldPriceToRedis(goldType, goldPrice); err != nil {
-		return fmt.Errorf("failed to save to Redis: %w", err)
+	// If not found in the store, crawl new data
+	log.Printf("Gold price for %s not found in store, crawling new data...", goldType)
+	if err := crawler.Refresh(ctx, goldType); err != nil {
+		return nil, fmt.Errorf("failed to crawl gold price: %w", err)
 	}
 
-	return nil
+	// Try to get again
+	goldPrice, err = store.GetGoldPrice(ctx, goldType)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get gold price: %w", err)
+	}
+	return goldPrice, nil
 }
 
-func crawlGoldPrice(goldType string) (*GoldPrice, error) {
-	url := fmt.Sprintf("https://24h.24hstatic.com/ajax/box_bieu_do_gia_vang/index/%s/0/0?is_template_page=1", goldType)
+// loadGoldPrices returns the stored snapshots for goldTypes. Missing types are
+// crawled in parallel; types that still cannot be loaded are left out.
+func loadGoldPrices(ctx context.Context, goldTypes []string) map[string]*GoldPrice {
+	result := make(map[string]*GoldPrice)
 
-	// Tạo HTTP request với các headers cần thiết
-	req, err := http.NewRequest("GET", url, nil)
-	if err != nil {
-		return nil, fmt.Errorf("failed to create request: %w", err)
-	}
-
-	// Thêm các headers theo yêu cầu của trang web
-	req.Header.Add("accept", "*/*")
-	req.Header.Add("accept-language", "vi-VN,vi;q=0.9,en-GB;q=0.8,en;q=0.7,ko-KR;q=0.6,ko;q=0.5,fr-FR;q=0.4,fr;q=0.3,en-US;q=0.2")
-	req.Header.Add("origin", "https://www.24h.com.vn")
-	req.Header.Add("priority", "u=1, i")
-	req.Header.Add("referer", "https://www.24h.com.vn/")
-	req.Header.Add("sec-ch-ua", `"Google Chrome";v="137", "Chromium";v="137", "Not/A)Brand";v="24"`)
-	req.Header.Add("sec-ch-ua-mobile", "?0")
-	req.Header.Add("sec-ch-ua-platform", `"macOS"`)
-	req.Header.Add("sec-fetch-dest", "empty")
-	req.Header.Add("sec-fetch-mode", "cors")
-	req.Header.Add("sec-fetch-site", "cross-site")
-	req.Header.Add("user-agent", "Mozilla/5.0 (Macintosh; Intel Mac OS X 10_15_7) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/137.0.0.0 Safari/537.36")
-
-	// Tạo HTTP client với timeout
-	client := &http.Client{
-		Timeout: 10 * time.Second,
-	}
-
-	// Gửi request
-	resp, err := client.Do(req)
-	if err != nil {
-		return nil, fmt.Errorf("HTTP request failed: %w", err)
+	var missing []string
+	for _, goldType := range goldTypes {
+		goldPrice, err := store.GetGoldPrice(ctx, goldType)
+		if err != nil {
+			missing = append(missing, goldType)
+			continue
+		}
+		revalidateIfStale(goldPrice)
+		result[goldType] = goldPrice
 	}
-	defer resp.Body.Close()
-
-	if resp.StatusCode != http.StatusOK {
-		return nil, fmt.Errorf("HTTP request returned status: %d", resp.StatusCode)
+	if len(missing) == 0 {
+		return result
 	}
 
-	// Đọc response body
-	body, err := io.ReadAll(resp.Body)
-	if err != nil {
-		return nil, fmt.Errorf("failed to read response body: %w", err)
-	}
-	fmt.Println("Crawled data successfully for gold type:", goldType, "with response length:", len(body), string(body)) // Log first 100 bytes for debugging
-	chartData, err := extractChartData(string(body))
-	if err != nil {
-		return nil, fmt.Errorf("failed to extract chart data: %w", err)
-	}
-	var buyPrices []float64
-	var sellPrices []float64
-	for _, series := range chartData.Series {
-		if series.Name == "Mua vào" {
-			buyPrices = series.Data
-		} else if series.Name == "Bán ra" {
-			sellPrices = series.Data
+	// Nếu không có trong store, thử crawl mới
+	logCrawlReport("cache miss", crawler.Run(ctx, missing))
+	for _, goldType := range missing {
+		// Thử lấy lại từ store sau khi crawl
+		goldPrice, err := store.GetGoldPrice(ctx, goldType)
+		if err != nil {
+			log.Printf("Still cannot get gold price for %s: %v", goldType, err)
+			continue
 		}
-		fmt.Printf("Series: %s\nData: %v\n", series.Name, series.Data)
+		result[goldType] = goldPrice
 	}
-	res := &GoldPrice{
-		Type:       goldType,
-		Dates:      chartData.Categories,
-		BuyPrices:  buyPrices,
-		SellPrices: sellPrices,
-		UpdatedAt:  time.Now(),
+	return result
+}
+
+// revalidateIfStale schedules a background refresh for snapshots older than
+// staleAfter.
+func revalidateIfStale(goldPrice *GoldPrice) {
+	if staleAfter > 0 && time.Since(goldPrice.UpdatedAt) > staleAfter {
+		crawler.RefreshInBackground(goldPrice.Type)
 	}
-	fmt.Println("Crawled gold price data:", res)
-	return res, nil
 }
 
-func saveGoldPriceToRedis(goldType string, goldPrice *GoldPrice) error {
-	key := redisKeyPrefix + goldType
+func getCrawlReportHandler(w http.ResponseWriter, r *http.Request) {
+	report := crawler.LastReport()
+	if report == nil {
+		respondWithError(w, http.StatusNotFound, "No crawl has run yet")
+		return
+	}
+	respondWithJSON(w, http.StatusOK, report)
+}
+
+func getGoldHistoryHandler(w http.ResponseWriter, r *http.Request) {
+	goldType := mux.Vars(r)["type"]
 
-	jsonData, err := json.Marshal(goldPrice)
+	query, err := parseSeriesQuery(r.URL.Query(), vntime.Now())
 	if err != nil {
-		return err
+		respondWithError(w, http.StatusBadRequest, err.Error())
+		return
 	}
 
-	return rdb.Set(ctx, key, jsonData, 0).Err()
-}
+	points, err := store.History(ctx, goldType, query.From, query.To)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, fmt.Sprintf("Failed to load history: %v", err))
+		return
+	}
 
-func getGoldPriceFromRedis(goldType string) (*GoldPrice, error) {
-	key := redisKeyPrefix + goldType
+	respondWithJSON(w, http.StatusOK, map[string]interface{}{
+		"type":   goldType,
+		"points": query.Apply(points),
+	})
+}
 
-	val, err := rdb.Get(ctx, key).Result()
+func crawlAndSaveGoldPrice(ctx context.Context, goldType string) (*GoldPrice, error) {
+	// Crawl data from the registered sources
+	goldPrice, err := sources.Fetch(ctx, goldType)
 	if err != nil {
-		return nil, err
+		return nil, fmt.Errorf("crawl failed: %w", err)
 	}
 
-	var goldPrice GoldPrice
-	if err := json.Unmarshal([]byte(val), &goldPrice); err != nil {
-		return nil, err
+	// Save the latest snapshot
+	if err := store.SaveGoldPrice(ctx, goldType, goldPrice); err != nil {
+		return nil, fmt.Errorf("failed to save gold price: %w", err)
 	}
 
-	return &goldPrice, nil
+	// Merge the chart window into the long-term history
+	added, updated, err := store.MergeHistory(ctx, goldType, historyFromGoldPrice(goldPrice))
+	if err != nil {
+		return nil, fmt.Errorf("failed to merge history: %w", err)
+	}
+	if added > 0 || updated > 0 {
+		log.Printf("History for %s: %d new, %d corrected points", goldType, added, updated)
+	}
+
+	return goldPrice, nil
 }
 
 func respondWithJSON(w http.ResponseWriter, code int, payload interface{}) {
@@ -450,91 +611,3 @@ func respondWithJSON(w http.ResponseWriter, code int, payload interface{}) {
 func respondWithError(w http.ResponseWriter, code int, message string) {
 	respondWithJSON(w, code, map[string]string{"error": message})
 }
-
-type Series struct {
-	Name  string
-	Color string
-	Data  []float64
-}
-
-type ChartData struct {
-	Categories []string
-	Series     []Series
-}
-
-func extractChartData(html string) (*ChartData, error) {
-	doc, err := goquery.NewDocumentFromReader(strings.NewReader(html))
-	if err != nil {
-		return nil, err
-	}
-
-	var scriptContent string
-	doc.Find("script").Each(func(i int, s *goquery.Selection) {
-		text := s.Text()
-		if strings.Contains(text, "highcharts") && strings.Contains(text, "categories") {
-			scriptContent = text
-		}
-	})
-
-	if scriptContent == "" {
-		return nil, fmt.Errorf("script chứa highcharts không được tìm thấy")
-	}
-
-	// Parse categories
-	catRegex := regexp.MustCompile(`categories:\s*\[(.*?)\]`)
-	catMatch := catRegex.FindStringSubmatch(scriptContent)
-	if len(catMatch) < 2 {
-		return nil, fmt.Errorf("không tìm thấy categories")
-	}
-	categoriesRaw := catMatch[1]
-	categories := parseStringArray(categoriesRaw)
-
-	// Parse series
-	seriesRegex := regexp.MustCompile(`name:\s*'(.*?)',\s*color:\s*'(.*?)',\s*data:\s*\[(.*?)\]`)
-	seriesMatches := seriesRegex.FindAllStringSubmatch(scriptContent, -1)
-
-	var seriesList []Series
-	for _, match := range seriesMatches {
-		name := match[1]
-		color := match[2]
-		dataRaw := match[3]
-		data := parseFloat64Array(dataRaw)
-		seriesList = append(seriesList, Series{
-			Name:  name,
-			Color: color,
-			Data:  data,
-		})
-	}
-
-	return &ChartData{
-		Categories: categories,
-		Series:     seriesList,
-	}, nil
-}
-
-func parseStringArray(input string) []string {
-	rawItems := strings.Split(input, ",")
-	var items []string
-	for _, item := range rawItems {
-		item = strings.TrimSpace(item)
-		item = strings.Trim(item, "'\"")
-		items = append(items, item)
-	}
-	return items
-}
-
-func parseFloat64Array(input string) []float64 {
-	rawItems := strings.Split(input, ",")
-	var items []float64
-	for _, item := range rawItems {
-		var v float64
-		_, err := fmt.Sscanf(strings.TrimSpace(item), "%f", &v)
-		if err != nil {
-			// Nếu có lỗi, có thể log và bỏ qua hoặc gán giá trị mặc định
-			log.Printf("Error parsing float value '%s': %v", item, err)
-			v = 0.0
-		}
-		items = append(items, v)
-	}
-	return items
-}
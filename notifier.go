@@ -0,0 +1,367 @@
+package main
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"log"
+	"os"
+	"path/filepath"
+	"slices"
+	"sync"
+	"time"
+
+	bottelegram "pricegoldtoday/bot"
+	"pricegoldtoday/vntime"
+)
+
+// eventVersion is bumped on incompatible changes to Event.
+const eventVersion = 1
+
+// Event types.
+const (
+	eventPriceUpdated   = "price.updated"
+	eventAlertFired     = "alert.fired"
+	eventDigestDaily    = "digest.daily"
+	eventDigestWeekly   = "digest.weekly"
+	eventCrawlCompleted = "crawl.completed"
+	eventSourceDown     = "source.down"
+)
+
+var eventTypes = []string{eventPriceUpdated, eventAlertFired, eventDigestDaily, eventDigestWeekly, eventCrawlCompleted, eventSourceDown}
+
+// Event is what notifiers deliver to outside systems.
+type Event struct {
+	Version int          `json:"version"`
+	ID      string       `json:"id"`
+	Type    string       `json:"type"`
+	Time    time.Time    `json:"time"`
+	Price   *PriceEvent  `json:"price,omitempty"`
+	Alert   *AlertEvent  `json:"alert,omitempty"`
+	Digest  *DigestEvent `json:"digest,omitempty"`
+	Crawl   *CrawlReport `json:"crawl,omitempty"`
+	Source  *SourceEvent `json:"source,omitempty"`
+}
+
+// PriceEvent is the latest daily price of a gold type, with the day before.
+type PriceEvent struct {
+	GoldType  string    `json:"gold_type"`
+	Name      string    `json:"name"`
+	Unit      string    `json:"unit"`
+	Date      string    `json:"date"`
+	Buy       float64   `json:"buy"`
+	Sell      float64   `json:"sell"`
+	PrevDate  string    `json:"prev_date,omitempty"`
+	PrevBuy   float64   `json:"prev_buy,omitempty"`
+	PrevSell  float64   `json:"prev_sell,omitempty"`
+	Source    string    `json:"source,omitempty"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// AlertEvent is an alert rule that started to hold.
+type AlertEvent struct {
+	RuleID    string  `json:"rule_id"`
+	GoldType  string  `json:"gold_type"`
+	Name      string  `json:"name"`
+	Kind      string  `json:"kind"`
+	Field     string  `json:"field,omitempty"`
+	Threshold float64 `json:"threshold"`
+	Value     float64 `json:"value"`
+	Date      string  `json:"date"`
+	// Description and Message are the rule and the alert as plain text, in
+	// Vietnamese. Chat channels render their own from the fields above.
+	Description string `json:"description"`
+	Message     string `json:"message"`
+}
+
+// DigestEvent is the daily or weekly price table. Providers hold the raw
+// chart window of each gold type; channels render it in their own language
+// and unit, comparing today with CompareDays ago. A weekly digest also
+// summarizes the stored history from From to To.
+type DigestEvent struct {
+	CompareDays int                         `json:"compare_days"`
+	Providers   []bottelegram.GoldPriceData `json:"providers"`
+	From        string                      `json:"from,omitempty"`
+	To          string                      `json:"to,omitempty"`
+	Weekly      []WeekSummary               `json:"weekly,omitempty"`
+}
+
+// WeekSummary is how the prices of a gold type moved over the week of a
+// weekly digest.
+type WeekSummary struct {
+	GoldType string     `json:"gold_type"`
+	Name     string     `json:"name"`
+	From     string     `json:"from"` // first day with prices
+	To       string     `json:"to"`   // last day with prices
+	Buy      PriceRange `json:"buy"`
+	Sell     PriceRange `json:"sell"`
+}
+
+// PriceRange is the first, last, highest and lowest price of a period.
+type PriceRange struct {
+	Open  float64 `json:"open"`
+	Close float64 `json:"close"`
+	High  float64 `json:"high"`
+	Low   float64 `json:"low"`
+}
+
+// summarizeWeek summarizes points, oldest first. ok is false without points.
+func summarizeWeek(provider Provider, points []PricePoint) (summary WeekSummary, ok bool) {
+	if len(points) == 0 {
+		return WeekSummary{}, false
+	}
+	summary = WeekSummary{
+		GoldType: provider.ID,
+		Name:     provider.Name,
+		From:     points[0].Date,
+		To:       points[len(points)-1].Date,
+	}
+	for i, p := range points {
+		for _, f := range []struct {
+			r *PriceRange
+			v float64
+		}{{&summary.Buy, p.Buy}, {&summary.Sell, p.Sell}} {
+			if i == 0 {
+				*f.r = PriceRange{Open: f.v, Close: f.v, High: f.v, Low: f.v}
+				continue
+			}
+			f.r.Close = f.v
+			f.r.High = max(f.r.High, f.v)
+			f.r.Low = min(f.r.Low, f.v)
+		}
+	}
+	return summary, true
+}
+
+// SourceEvent is an upstream whose circuit breaker opened; it is skipped
+// until RetryAt.
+type SourceEvent struct {
+	Name     string    `json:"name"`
+	Error    string    `json:"error"`
+	Failures int       `json:"consecutive_failures"`
+	OpenedAt time.Time `json:"opened_at"`
+	RetryAt  time.Time `json:"retry_at"`
+}
+
+func newEvent(eventType string) Event {
+	b := make([]byte, 8)
+	rand.Read(b)
+	return Event{Version: eventVersion, ID: hex.EncodeToString(b), Type: eventType, Time: time.Now().UTC()}
+}
+
+// Notifier delivers events to one outside system. Notify retries on its own
+// and returns an error only once the event is given up on.
+type Notifier interface {
+	Name() string
+	Notify(ctx context.Context, ev Event) error
+}
+
+// NotifierHub fans events out to every notifier in the background and
+// records the ones that could not be delivered in the dead-letter log.
+type NotifierHub struct {
+	notifiers  []Notifier
+	timeout    time.Duration
+	deadLetter *deadLetterLog
+
+	mu        sync.Mutex
+	lastPrice map[string]PricePoint
+}
+
+func NewNotifierHub(notifiers []Notifier, timeout time.Duration, deadLetterPath string) *NotifierHub {
+	h := &NotifierHub{
+		notifiers: notifiers,
+		timeout:   timeout,
+		lastPrice: make(map[string]PricePoint),
+	}
+	if deadLetterPath != "" {
+		h.deadLetter = &deadLetterLog{path: deadLetterPath}
+	}
+	return h
+}
+
+// Publish sends ev to every notifier without waiting for delivery. A nil
+// hub or one without notifiers drops the event.
+func (h *NotifierHub) Publish(ev Event) {
+	if h == nil {
+		return
+	}
+	for _, n := range h.notifiers {
+		go func(n Notifier) {
+			ctx, cancel := context.WithTimeout(context.Background(), h.timeout)
+			defer cancel()
+			if err := n.Notify(ctx, ev); err != nil {
+				log.Printf("Notifier %s failed to deliver %s event %s: %v", n.Name(), ev.Type, ev.ID, err)
+				h.deadLetter.Record(n.Name(), ev, err)
+			}
+		}(n)
+	}
+}
+
+// PriceHook is a CrawlHook publishing price.updated when the latest daily
+// price of a gold type differs from the last one published.
+func (h *NotifierHub) PriceHook(ctx context.Context, goldType string, goldPrice *GoldPrice) {
+	if h == nil || len(h.notifiers) == 0 {
+		return
+	}
+	points := historyFromGoldPrice(goldPrice)
+	if len(points) == 0 {
+		return
+	}
+	latest := points[len(points)-1]
+
+	h.mu.Lock()
+	changed := !h.lastPrice[goldType].samePrice(latest)
+	h.lastPrice[goldType] = latest
+	h.mu.Unlock()
+	if !changed {
+		return
+	}
+
+	provider, _ := findProvider(goldType)
+	ev := newEvent(eventPriceUpdated)
+	ev.Price = &PriceEvent{
+		GoldType:  goldType,
+		Name:      provider.Name,
+		Unit:      priceUnit,
+		Date:      latest.Date,
+		Buy:       latest.Buy,
+		Sell:      latest.Sell,
+		Source:    goldPrice.Source,
+		UpdatedAt: goldPrice.UpdatedAt,
+	}
+	if len(points) > 1 {
+		prev := points[len(points)-2]
+		ev.Price.PrevDate, ev.Price.PrevBuy, ev.Price.PrevSell = prev.Date, prev.Buy, prev.Sell
+	}
+	h.Publish(ev)
+}
+
+// PublishAlert publishes alert.fired for a rule that started to hold.
+func (h *NotifierHub) PublishAlert(rule *AlertRule, provider Provider, reading alertReading) {
+	ev := newEvent(eventAlertFired)
+	ev.Alert = &AlertEvent{
+		RuleID:    rule.ID,
+		GoldType:  rule.GoldType,
+		Name:      provider.Name,
+		Kind:      rule.Kind,
+		Field:     rule.Field,
+		Threshold: rule.Threshold,
+		Value:     reading.Value,
+		Date:      reading.Date,
+	}
+	ev.Alert.Message, ev.Alert.Description = defaultAlertText.event(ev.Alert)
+	h.Publish(ev)
+}
+
+// PublishDigest publishes digest.daily or digest.weekly with the current
+// prices of every gold type. The weekly digest adds a summary of the last
+// seven days of history.
+func (h *NotifierHub) PublishDigest(ctx context.Context, eventType string) {
+	if h == nil || len(h.notifiers) == 0 {
+		return
+	}
+	data := goldPriceResponseFor(&Subscriber{}, loadGoldPrices(ctx, GOLDTYPES))
+	if len(data.Providers) == 0 {
+		log.Println("No prices for the notifier digest")
+		return
+	}
+	ev := newEvent(eventType)
+	ev.Digest = &DigestEvent{CompareDays: 1, Providers: data.Providers}
+	if eventType == eventDigestWeekly {
+		ev.Digest.CompareDays = 7
+		to := vntime.Today()
+		from := to.AddDate(0, 0, -6)
+		ev.Digest.From, ev.Digest.To = from.Format(historyDateLayout), to.Format(historyDateLayout)
+		ev.Digest.Weekly = weekSummaries(ctx, from, to)
+		if len(ev.Digest.Weekly) == 0 {
+			log.Println("No history for the weekly notifier digest")
+			return
+		}
+	}
+	h.Publish(ev)
+}
+
+// weekSummaries summarizes the history of every enabled gold type between
+// from and to.
+func weekSummaries(ctx context.Context, from, to time.Time) []WeekSummary {
+	var res []WeekSummary
+	for _, provider := range enabledProviders() {
+		points, err := store.History(ctx, provider.ID, from, to)
+		if err != nil {
+			log.Printf("Cannot load %s history for the weekly digest: %v", provider.ID, err)
+			continue
+		}
+		if summary, ok := summarizeWeek(provider, points); ok {
+			res = append(res, summary)
+		}
+	}
+	return res
+}
+
+// PublishCrawl is a BatchHook publishing crawl.completed.
+func (h *NotifierHub) PublishCrawl(report *CrawlReport) {
+	ev := newEvent(eventCrawlCompleted)
+	ev.Crawl = report
+	h.Publish(ev)
+}
+
+// PublishSourceDown is a SourceDownHook publishing source.down.
+func (h *NotifierHub) PublishSourceDown(source string, status BreakerStatus, err error) {
+	ev := newEvent(eventSourceDown)
+	ev.Source = &SourceEvent{Name: source, Error: err.Error(), Failures: status.Failures}
+	if status.OpenedAt != nil {
+		ev.Source.OpenedAt = *status.OpenedAt
+		ev.Source.RetryAt = status.OpenedAt.Add(breakerCooldown)
+	}
+	h.Publish(ev)
+}
+
+// wantsEvent reports whether a notifier configured with events takes eventType;
+// an empty list takes everything.
+func wantsEvent(events []string, eventType string) bool {
+	return len(events) == 0 || slices.Contains(events, eventType)
+}
+
+// deadLetterLog appends undeliverable events to a JSON Lines file so they
+// can be inspected or replayed.
+type deadLetterLog struct {
+	path string
+	mu   sync.Mutex
+}
+
+type deadLetter struct {
+	Time     time.Time `json:"time"`
+	Notifier string    `json:"notifier"`
+	Error    string    `json:"error"`
+	Event    Event     `json:"event"`
+}
+
+// Record is a no-op on a nil log.
+func (d *deadLetterLog) Record(notifier string, ev Event, deliveryErr error) {
+	if d == nil {
+		return
+	}
+	line, err := json.Marshal(deadLetter{Time: time.Now().UTC(), Notifier: notifier, Error: deliveryErr.Error(), Event: ev})
+	if err != nil {
+		log.Printf("Cannot encode dead letter: %v", err)
+		return
+	}
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	if err := os.MkdirAll(filepath.Dir(d.path), 0o755); err != nil {
+		log.Printf("Cannot write dead letter: %v", err)
+		return
+	}
+	f, err := os.OpenFile(d.path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0o644)
+	if err != nil {
+		log.Printf("Cannot write dead letter: %v", err)
+		return
+	}
+	defer f.Close()
+	if _, err := f.Write(append(line, '\n')); err != nil {
+		log.Printf("Cannot write dead letter: %v", err)
+	}
+}
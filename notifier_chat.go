@@ -0,0 +1,160 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+
+	bottelegram "pricegoldtoday/bot"
+)
+
+// chatLabels are the fixed strings of messages not rendered from the digest
+// templates: the digest in chat channels other than Telegram, and the
+// Telegram command replies and chart captions.
+type chatLabels struct {
+	Title    string // takes the date
+	Weekly   string // takes the first and last day of the week
+	Dealer   string
+	Buy      string
+	Sell     string
+	Unit     string
+	Compared string // takes the date compared with
+	Updated  string // takes the update time
+	Alert    string
+	DateFmt  string
+	TimeFmt  string
+	DayFmt   string // a full date, as in price tables
+
+	Open   string // weekly digest columns
+	Close  string
+	Change string
+	High   string
+	Low    string
+
+	Chart         string // caption, takes the number of days
+	ChartTitle    string // takes the unit
+	History       string // takes the gold type name and the number of days
+	HistoryHeader string // aligned with the rows of /history
+	NoHistory     string // takes the number of days
+}
+
+var chatLabelsByLocale = map[string]chatLabels{
+	"vi": {
+		Title:    "💰 Bảng giá vàng ngày %s",
+		Weekly:   "📅 Giá vàng tuần %s – %s",
+		Dealer:   "Cửa hàng",
+		Buy:      "Mua vào",
+		Sell:     "Bán ra",
+		Unit:     "Đơn vị: %s",
+		Compared: "So sánh với ngày %s",
+		Updated:  "Cập nhật: %s",
+		Alert:    "🔔 Cảnh báo giá vàng",
+		DateFmt:  "02/01",
+		TimeFmt:  "15:04 02/01/2006",
+		DayFmt:   "02/01/2006",
+
+		Open:   "Đầu tuần",
+		Close:  "Cuối tuần",
+		Change: "Thay đổi",
+		High:   "Cao nhất",
+		Low:    "Thấp nhất",
+
+		Chart:         "Giá vàng %d ngày qua",
+		ChartTitle:    "Giá vàng (%s)",
+		History:       "%s - %d ngày",
+		HistoryHeader: "NGÀY        MUA VÀO  BÁN RA",
+		NoHistory:     "Chưa có lịch sử giá trong %d ngày qua.",
+	},
+	"en": {
+		Title:    "💰 Gold prices %s",
+		Weekly:   "📅 Gold prices, week of %s – %s",
+		Dealer:   "Dealer",
+		Buy:      "Buy",
+		Sell:     "Sell",
+		Unit:     "Unit: %s",
+		Compared: "Compared with %s",
+		Updated:  "Updated: %s (Vietnam time)",
+		Alert:    "🔔 Gold price alert",
+		DateFmt:  "Jan 02",
+		TimeFmt:  "15:04 Jan 02, 2006",
+		DayFmt:   "2006-01-02",
+
+		Open:   "Open",
+		Close:  "Close",
+		Change: "Change",
+		High:   "High",
+		Low:    "Low",
+
+		Chart:         "Gold prices, last %d days",
+		ChartTitle:    "Gold prices (%s)",
+		History:       "%s - %d days",
+		HistoryHeader: "DATE            BUY    SELL",
+		NoHistory:     "No price history in the last %d days.",
+	},
+}
+
+func labelsFor(locale string) chatLabels {
+	if l, ok := chatLabelsByLocale[locale]; ok {
+		return l
+	}
+	return chatLabelsByLocale[bottelegram.DefaultLocale]
+}
+
+// chatNotifier holds what the Discord and Slack notifiers share: where to
+// post, which events to take and how to render the digest.
+type chatNotifier struct {
+	name   string
+	url    string
+	events []string
+	opts   bottelegram.DigestOptions
+	client *http.Client
+	retry  retryPolicy
+}
+
+func newChatNotifier(cfg ChatNotifierConfig) chatNotifier {
+	events := cfg.Events
+	if len(events) == 0 {
+		// Raw price updates are too chatty for a channel.
+		events = []string{eventDigestDaily, eventAlertFired}
+	}
+	return chatNotifier{
+		name:   cfg.Name,
+		url:    cfg.URL,
+		events: events,
+		opts:   bottelegram.DigestOptions{Locale: cfg.Language, Unit: cfg.Unit},
+		client: &http.Client{Timeout: cfg.Timeout.Duration},
+		retry:  defaultRetryPolicy,
+	}
+}
+
+func (n *chatNotifier) Name() string {
+	return n.name
+}
+
+// digest builds the digest of a digest event in the channel's language and
+// unit.
+func (n *chatNotifier) digest(ev Event) bottelegram.Digest {
+	return buildEventDigest(ev, n.opts)
+}
+
+// alert renders an alert event in the channel's language and unit.
+func (n *chatNotifier) alert(ev Event) (message, description string) {
+	return alertTextFor(n.opts).event(ev.Alert)
+}
+
+func buildEventDigest(ev Event, opts bottelegram.DigestOptions) bottelegram.Digest {
+	data := &bottelegram.GoldPriceResponse{Providers: ev.Digest.Providers}
+	opts.CompareDays = ev.Digest.CompareDays
+	return bottelegram.BuildDigest(data, opts, ev.Time)
+}
+
+// post sends payload as JSON, retrying like the signed webhooks do.
+func (n *chatNotifier) post(ctx context.Context, payload any) error {
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return err
+	}
+	return n.retry.Do(ctx, func(ctx context.Context) error {
+		return postJSON(ctx, n.client, n.url, body, nil)
+	})
+}
@@ -0,0 +1,161 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	bottelegram "pricegoldtoday/bot"
+	"pricegoldtoday/vntime"
+)
+
+// chatEvents are a daily digest and an alert as of 10:00 on 16 March 2025.
+func chatEvents() (digest, alert Event) {
+	now := time.Date(2025, 3, 16, 10, 0, 0, 0, vntime.Location)
+
+	digest = newEvent(eventDigestDaily)
+	digest.Time = now
+	digest.Digest = &DigestEvent{CompareDays: 1, Providers: []bottelegram.GoldPriceData{{
+		Type:       "sjc",
+		Name:       "SJC",
+		Dates:      []string{"15/03", "16/03"},
+		BuyPrices:  []float64{118e6, 118.5e6},
+		SellPrices: []float64{120e6, 121e6},
+	}}}
+
+	alert = newEvent(eventAlertFired)
+	alert.Time = now
+	alert.Alert = &AlertEvent{RuleID: "a1", GoldType: "sjc", Name: "SJC", Kind: alertAbove, Field: "sell", Threshold: 120e6, Value: 121e6, Date: "2025-03-16"}
+	return digest, alert
+}
+
+// capturePosts serves a webhook that keeps the body of every POST.
+func capturePosts(t *testing.T) (url string, bodies func() [][]byte) {
+	var got [][]byte
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		got = append(got, body)
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	t.Cleanup(srv.Close)
+	return srv.URL, func() [][]byte { return got }
+}
+
+func TestDiscordNotifierPayloads(t *testing.T) {
+	tests := []struct {
+		language, unit string
+		title          string
+		field          string
+		footer         string
+		alert          string
+	}{
+		{"vi", "luong", "💰 Bảng giá vàng ngày 16/03", "Mua vào: **118.5** ↑0.5 (0.4%)\nBán ra: **121.0** ↑1.0 (0.8%)", "Đơn vị: triệu đồng/lượng", "SJC (16/03/2025): giá bán 121.0 tr, vượt 120.0 tr"},
+		{"en", "luong", "💰 Gold prices Mar 16", "Buy: **118.5** ↑0.5 (0.4%)\nSell: **121.0** ↑1.0 (0.8%)", "Unit: million VND/tael", "SJC (2025-03-16): sell price 121.0 M VND/tael, above 120.0 M VND/tael"},
+		{"en", "chi", "💰 Gold prices Mar 16", "Buy: **11850** ↑50 (0.4%)\nSell: **12100** ↑100 (0.8%)", "Unit: thousand VND/chi", "SJC (2025-03-16): sell price 12100 k VND/chi, above 12000 k VND/chi"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.language+"/"+tt.unit, func(t *testing.T) {
+			url, bodies := capturePosts(t)
+			n := newDiscordNotifier(ChatNotifierConfig{Name: "discord", URL: url, Language: tt.language, Unit: tt.unit, Timeout: Duration{time.Second}})
+
+			digest, alert := chatEvents()
+			for _, ev := range []Event{digest, alert, newEvent(eventPriceUpdated)} {
+				if err := n.Notify(context.Background(), ev); err != nil {
+					t.Fatal(err)
+				}
+			}
+			posts := bodies()
+			if len(posts) != 2 {
+				t.Fatalf("%d posts, want the digest and the alert only", len(posts))
+			}
+
+			var msg discordMessage
+			if err := json.Unmarshal(posts[0], &msg); err != nil {
+				t.Fatal(err)
+			}
+			embed := msg.Embeds[0]
+			if embed.Title != tt.title || embed.Color != discordGold || embed.Timestamp != "2025-03-16T10:00:00+07:00" {
+				t.Errorf("digest embed = %q, %#x, %q", embed.Title, embed.Color, embed.Timestamp)
+			}
+			if len(embed.Fields) != 1 || embed.Fields[0].Name != "SJC" || embed.Fields[0].Value != tt.field || !embed.Fields[0].Inline {
+				t.Errorf("fields = %+v, want SJC with %q", embed.Fields, tt.field)
+			}
+			if embed.Footer == nil || !strings.HasPrefix(embed.Footer.Text, tt.footer) {
+				t.Errorf("footer = %+v, want it to start with %q", embed.Footer, tt.footer)
+			}
+
+			if err := json.Unmarshal(posts[1], &msg); err != nil {
+				t.Fatal(err)
+			}
+			embed = msg.Embeds[0]
+			if embed.Title != labelsFor(tt.language).Alert || embed.Description != tt.alert {
+				t.Errorf("alert embed = %q: %q, want %q", embed.Title, embed.Description, tt.alert)
+			}
+		})
+	}
+}
+
+func TestSlackNotifierPayloads(t *testing.T) {
+	tests := []struct {
+		language string
+		title    string
+		field    string
+		alert    string
+	}{
+		{"vi", "💰 Bảng giá vàng ngày 16/03", "*SJC*\nMua vào: `118.5` ↑0.5 (0.4%)\nBán ra: `121.0` ↑1.0 (0.8%)", "SJC (16/03/2025): giá bán 121.0 tr, vượt 120.0 tr"},
+		{"en", "💰 Gold prices Mar 16", "*SJC*\nBuy: `118.5` ↑0.5 (0.4%)\nSell: `121.0` ↑1.0 (0.8%)", "SJC (2025-03-16): sell price 121.0 M VND/tael, above 120.0 M VND/tael"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.language, func(t *testing.T) {
+			url, bodies := capturePosts(t)
+			n := newSlackNotifier(ChatNotifierConfig{Name: "slack", URL: url, Language: tt.language, Unit: "luong", Timeout: Duration{time.Second}})
+
+			digest, alert := chatEvents()
+			for _, ev := range []Event{digest, alert} {
+				if err := n.Notify(context.Background(), ev); err != nil {
+					t.Fatal(err)
+				}
+			}
+			posts := bodies()
+			if len(posts) != 2 {
+				t.Fatalf("%d posts, want 2", len(posts))
+			}
+
+			var msg slackMessage
+			if err := json.Unmarshal(posts[0], &msg); err != nil {
+				t.Fatal(err)
+			}
+			if msg.Text != tt.title || len(msg.Blocks) != 3 {
+				t.Fatalf("digest = %q with %d blocks", msg.Text, len(msg.Blocks))
+			}
+			if b := msg.Blocks[0]; b.Type != "header" || b.Text.Text != tt.title {
+				t.Errorf("header = %+v", b)
+			}
+			if b := msg.Blocks[1]; b.Type != "section" || len(b.Fields) != 1 || b.Fields[0].Text != tt.field {
+				t.Errorf("section = %+v, want %q", b, tt.field)
+			}
+			if b := msg.Blocks[2]; b.Type != "context" || len(b.Elements) != 3 {
+				t.Errorf("context = %+v", b)
+			}
+
+			msg = slackMessage{}
+			if err := json.Unmarshal(posts[1], &msg); err != nil {
+				t.Fatal(err)
+			}
+			if len(msg.Blocks) != 3 || msg.Blocks[1].Text.Text != tt.alert {
+				t.Errorf("alert = %+v, want %q", msg.Blocks, tt.alert)
+			}
+		})
+	}
+}
+
+func TestSlackEscape(t *testing.T) {
+	if got := slackEscape("<b>Vàng & bạc</b>"); got != "&lt;b&gt;Vàng &amp; bạc&lt;/b&gt;" {
+		t.Errorf("slackEscape = %q", got)
+	}
+}
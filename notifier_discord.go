@@ -0,0 +1,90 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"time"
+)
+
+// discordGold is the embed color, #D4AF37.
+const discordGold = 0xD4AF37
+
+type discordMessage struct {
+	Embeds []discordEmbed `json:"embeds"`
+}
+
+type discordEmbed struct {
+	Title       string         `json:"title"`
+	Description string         `json:"description,omitempty"`
+	Color       int            `json:"color"`
+	Fields      []discordField `json:"fields,omitempty"`
+	Footer      *discordFooter `json:"footer,omitempty"`
+	Timestamp   string         `json:"timestamp,omitempty"`
+}
+
+type discordField struct {
+	Name   string `json:"name"`
+	Value  string `json:"value"`
+	Inline bool   `json:"inline"`
+}
+
+type discordFooter struct {
+	Text string `json:"text"`
+}
+
+// discordNotifier posts the daily digest and alerts to a Discord channel
+// webhook as embeds, one inline field per provider.
+type discordNotifier struct {
+	chatNotifier
+}
+
+func newDiscordNotifier(cfg ChatNotifierConfig) *discordNotifier {
+	return &discordNotifier{newChatNotifier(cfg)}
+}
+
+func (n *discordNotifier) Notify(ctx context.Context, ev Event) error {
+	if !wantsEvent(n.events, ev.Type) {
+		return nil
+	}
+	var embed discordEmbed
+	switch {
+	case ev.Type == eventDigestDaily && ev.Digest != nil:
+		embed = n.digestEmbed(ev)
+	case ev.Type == eventAlertFired && ev.Alert != nil:
+		message, description := n.alert(ev)
+		embed = discordEmbed{
+			Title:       labelsFor(n.opts.Locale).Alert,
+			Description: message,
+			Color:       discordGold,
+			Footer:      &discordFooter{Text: description},
+			Timestamp:   ev.Time.Format(time.RFC3339),
+		}
+	default:
+		return nil
+	}
+	return n.post(ctx, discordMessage{Embeds: []discordEmbed{embed}})
+}
+
+func (n *discordNotifier) digestEmbed(ev Event) discordEmbed {
+	labels := labelsFor(n.opts.Locale)
+	digest := n.digest(ev)
+
+	embed := discordEmbed{
+		Title:     fmt.Sprintf(labels.Title, digest.Date.Format(labels.DateFmt)),
+		Color:     discordGold,
+		Timestamp: ev.Time.Format(time.RFC3339),
+		Footer: &discordFooter{Text: fmt.Sprintf(labels.Unit, digest.Unit) + " · " +
+			fmt.Sprintf(labels.Compared, digest.PrevDate.Format(labels.DateFmt)) + " · " +
+			fmt.Sprintf(labels.Updated, digest.UpdatedAt.Format(labels.TimeFmt))},
+	}
+	// Discord allows 25 fields per embed.
+	for _, row := range digest.Rows[:min(len(digest.Rows), 25)] {
+		embed.Fields = append(embed.Fields, discordField{
+			Name: row.Name,
+			Value: fmt.Sprintf("%s: **%s** %s\n%s: **%s** %s",
+				labels.Buy, row.Buy, row.BuyChange, labels.Sell, row.Sell, row.SellChange),
+			Inline: true,
+		})
+	}
+	return embed
+}
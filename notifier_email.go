@@ -0,0 +1,299 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"fmt"
+	htmltemplate "html/template"
+	"mime"
+	"mime/multipart"
+	"mime/quotedprintable"
+	"net"
+	"net/mail"
+	"net/smtp"
+	"net/textproto"
+	"strconv"
+	"strings"
+	"time"
+
+	bottelegram "pricegoldtoday/bot"
+	"pricegoldtoday/vntime"
+)
+
+// emailNotifier mails the daily and weekly digests over SMTP as
+// multipart/alternative messages: an HTML table with a plain-text fallback.
+// The daily email compares today's prices with yesterday's; the weekly one
+// summarizes the week's history of every gold type.
+type emailNotifier struct {
+	name   string
+	addr   string // host:port
+	host   string
+	auth   smtp.Auth // nil without credentials
+	from   *mail.Address
+	to     []*mail.Address
+	events []string
+	opts   bottelegram.DigestOptions
+	retry  retryPolicy
+}
+
+// newEmailNotifier expects a validated config.
+func newEmailNotifier(cfg EmailNotifierConfig) (*emailNotifier, error) {
+	from, err := mail.ParseAddress(cfg.From)
+	if err != nil {
+		return nil, fmt.Errorf("invalid from address: %w", err)
+	}
+	to, err := mail.ParseAddressList(strings.Join(cfg.To, ", "))
+	if err != nil {
+		return nil, fmt.Errorf("invalid to address: %w", err)
+	}
+	events := cfg.Events
+	if len(events) == 0 {
+		events = []string{eventDigestDaily, eventDigestWeekly}
+	}
+	n := &emailNotifier{
+		name:   cfg.Name,
+		addr:   net.JoinHostPort(cfg.Host, strconv.Itoa(cfg.Port)),
+		host:   cfg.Host,
+		from:   from,
+		to:     to,
+		events: events,
+		opts:   bottelegram.DigestOptions{Locale: cfg.Language, Unit: cfg.Unit},
+		retry:  defaultRetryPolicy,
+	}
+	if cfg.Username != "" {
+		n.auth = smtp.PlainAuth("", cfg.Username, cfg.Password, cfg.Host)
+	}
+	return n, nil
+}
+
+func (n *emailNotifier) Name() string {
+	return n.name
+}
+
+func (n *emailNotifier) Notify(ctx context.Context, ev Event) error {
+	if !wantsEvent(n.events, ev.Type) || ev.Digest == nil {
+		return nil
+	}
+	msg, err := n.message(ev)
+	if err != nil {
+		return err
+	}
+	return n.retry.Do(ctx, func(ctx context.Context) error {
+		return n.send(ctx, msg)
+	})
+}
+
+// send delivers msg in one SMTP session, upgrading to TLS when the server
+// offers STARTTLS.
+func (n *emailNotifier) send(ctx context.Context, msg []byte) error {
+	var d net.Dialer
+	conn, err := d.DialContext(ctx, "tcp", n.addr)
+	if err != nil {
+		return err
+	}
+	if deadline, ok := ctx.Deadline(); ok {
+		conn.SetDeadline(deadline)
+	}
+	c, err := smtp.NewClient(conn, n.host)
+	if err != nil {
+		conn.Close()
+		return err
+	}
+	defer c.Close()
+
+	if ok, _ := c.Extension("STARTTLS"); ok {
+		if err := c.StartTLS(&tls.Config{ServerName: n.host}); err != nil {
+			return err
+		}
+	}
+	if n.auth != nil {
+		if err := c.Auth(n.auth); err != nil {
+			return err
+		}
+	}
+	if err := c.Mail(n.from.Address); err != nil {
+		return err
+	}
+	for _, to := range n.to {
+		if err := c.Rcpt(to.Address); err != nil {
+			return err
+		}
+	}
+	w, err := c.Data()
+	if err != nil {
+		return err
+	}
+	if _, err := w.Write(msg); err != nil {
+		return err
+	}
+	if err := w.Close(); err != nil {
+		return err
+	}
+	return c.Quit()
+}
+
+// emailDigest is the data of emailHTML.
+type emailDigest struct {
+	bottelegram.Digest
+	Title  string
+	Labels chatLabels
+}
+
+var emailHTML = htmltemplate.Must(htmltemplate.New("email").Parse(`<!DOCTYPE html>
+<html><body style="font-family: Arial, sans-serif; color: #222;">
+<h2 style="color: #b8860b;">{{.Title}}</h2>
+<table cellpadding="6" cellspacing="0" style="border-collapse: collapse; border: 1px solid #ddd;">
+<tr style="background: #f5e6b8;"><th align="left">{{.Labels.Dealer}}</th><th align="right">{{.Labels.Buy}}</th><th align="left"></th><th align="right">{{.Labels.Sell}}</th><th align="left"></th></tr>
+{{range .Rows}}<tr style="border-top: 1px solid #ddd;"><td>{{.Name}}</td><td align="right"><b>{{.Buy}}</b></td><td>{{.BuyChange}}</td><td align="right"><b>{{.Sell}}</b></td><td>{{.SellChange}}</td></tr>
+{{end}}</table>
+<p style="color: #666; font-size: 90%;">{{printf .Labels.Unit .Unit}}<br>
+{{printf .Labels.Compared (.PrevDate.Format .Labels.DateFmt)}}<br>
+{{printf .Labels.Updated (.UpdatedAt.Format .Labels.TimeFmt)}}</p>
+</body></html>
+`))
+
+// emailWeek is the data of emailWeeklyHTML.
+type emailWeek struct {
+	Title   string
+	Labels  chatLabels
+	Unit    string
+	Updated string
+	Rows    []emailWeekRow
+}
+
+// emailWeekRow is the buy or sell price of a gold type over the week; Name
+// is only set on the first row of each gold type.
+type emailWeekRow struct {
+	Name, Field                    string
+	Open, Close, Change, High, Low string
+}
+
+var emailWeeklyHTML = htmltemplate.Must(htmltemplate.New("email-weekly").Parse(`<!DOCTYPE html>
+<html><body style="font-family: Arial, sans-serif; color: #222;">
+<h2 style="color: #b8860b;">{{.Title}}</h2>
+<table cellpadding="6" cellspacing="0" style="border-collapse: collapse; border: 1px solid #ddd;">
+<tr style="background: #f5e6b8;"><th align="left">{{.Labels.Dealer}}</th><th align="left"></th><th align="right">{{.Labels.Open}}</th><th align="right">{{.Labels.Close}}</th><th align="left">{{.Labels.Change}}</th><th align="right">{{.Labels.High}}</th><th align="right">{{.Labels.Low}}</th></tr>
+{{range .Rows}}<tr{{if .Name}} style="border-top: 1px solid #ddd;"{{end}}><td>{{.Name}}</td><td>{{.Field}}</td><td align="right">{{.Open}}</td><td align="right"><b>{{.Close}}</b></td><td>{{.Change}}</td><td align="right">{{.High}}</td><td align="right">{{.Low}}</td></tr>
+{{end}}</table>
+<p style="color: #666; font-size: 90%;">{{printf .Labels.Unit .Unit}}<br>
+{{printf .Labels.Updated .Updated}}</p>
+</body></html>
+`))
+
+// dailyBody renders the daily table compared with yesterday.
+func (n *emailNotifier) dailyBody(ev Event) (title, text, html string, err error) {
+	labels := labelsFor(n.opts.Locale)
+	digest := buildEventDigest(ev, n.opts)
+	title = fmt.Sprintf(labels.Title, digest.Date.Format(labels.DateFmt))
+
+	var sb strings.Builder
+	sb.WriteString(title + "\n\n")
+	for _, row := range digest.Rows {
+		fmt.Fprintf(&sb, "%s\n  %s: %s %s\n  %s: %s %s\n\n", row.Name, labels.Buy, row.Buy, row.BuyChange, labels.Sell, row.Sell, row.SellChange)
+	}
+	fmt.Fprintf(&sb, labels.Unit+"\n", digest.Unit)
+	fmt.Fprintf(&sb, labels.Compared+"\n", digest.PrevDate.Format(labels.DateFmt))
+	fmt.Fprintf(&sb, labels.Updated+"\n", digest.UpdatedAt.Format(labels.TimeFmt))
+
+	var buf bytes.Buffer
+	if err := emailHTML.Execute(&buf, emailDigest{Digest: digest, Title: title, Labels: labels}); err != nil {
+		return "", "", "", err
+	}
+	return title, sb.String(), buf.String(), nil
+}
+
+// weeklyBody renders the week's open, close, change, high and low of every
+// gold type.
+func (n *emailNotifier) weeklyBody(ev Event) (title, text, html string, err error) {
+	labels := labelsFor(n.opts.Locale)
+	unit := n.opts.Unit
+	title = fmt.Sprintf(labels.Weekly, formatDay(ev.Digest.From, labels.DateFmt), formatDay(ev.Digest.To, labels.DateFmt))
+
+	week := emailWeek{
+		Title:   title,
+		Labels:  labels,
+		Unit:    bottelegram.UnitLabel(unit, n.opts.Locale),
+		Updated: ev.Time.In(vntime.Location).Format(labels.TimeFmt),
+	}
+	var sb strings.Builder
+	sb.WriteString(title + "\n\n")
+	for _, s := range ev.Digest.Weekly {
+		sb.WriteString(s.Name + "\n")
+		for i, f := range []struct {
+			label string
+			r     PriceRange
+		}{{labels.Buy, s.Buy}, {labels.Sell, s.Sell}} {
+			row := emailWeekRow{
+				Field:  f.label,
+				Open:   bottelegram.FormatPrice(f.r.Open, unit),
+				Close:  bottelegram.FormatPrice(f.r.Close, unit),
+				Change: bottelegram.FormatChange(f.r.Close, f.r.Open, unit),
+				High:   bottelegram.FormatPrice(f.r.High, unit),
+				Low:    bottelegram.FormatPrice(f.r.Low, unit),
+			}
+			if i == 0 {
+				row.Name = s.Name
+			}
+			week.Rows = append(week.Rows, row)
+			fmt.Fprintf(&sb, "  %s: %s %s → %s %s %s, %s %s, %s %s\n", row.Field, labels.Open, row.Open, labels.Close, row.Close,
+				row.Change, labels.High, row.High, labels.Low, row.Low)
+		}
+		sb.WriteString("\n")
+	}
+	fmt.Fprintf(&sb, labels.Unit+"\n", week.Unit)
+	fmt.Fprintf(&sb, labels.Updated+"\n", week.Updated)
+
+	var buf bytes.Buffer
+	if err := emailWeeklyHTML.Execute(&buf, week); err != nil {
+		return "", "", "", err
+	}
+	return title, sb.String(), buf.String(), nil
+}
+
+// message renders ev as a complete RFC 5322 message.
+func (n *emailNotifier) message(ev Event) ([]byte, error) {
+	render := n.dailyBody
+	if ev.Type == eventDigestWeekly {
+		render = n.weeklyBody
+	}
+	title, text, html, err := render(ev)
+	if err != nil {
+		return nil, err
+	}
+
+	var body bytes.Buffer
+	mw := multipart.NewWriter(&body)
+	for _, part := range []struct{ contentType, content string }{
+		{"text/plain; charset=utf-8", text},
+		{"text/html; charset=utf-8", html},
+	} {
+		w, err := mw.CreatePart(textproto.MIMEHeader{
+			"Content-Type":              {part.contentType},
+			"Content-Transfer-Encoding": {"quoted-printable"},
+		})
+		if err != nil {
+			return nil, err
+		}
+		qp := quotedprintable.NewWriter(w)
+		qp.Write([]byte(part.content))
+		qp.Close()
+	}
+	mw.Close()
+
+	to := make([]string, len(n.to))
+	for i, addr := range n.to {
+		to[i] = addr.String()
+	}
+	var msg bytes.Buffer
+	fmt.Fprintf(&msg, "From: %s\r\n", n.from)
+	fmt.Fprintf(&msg, "To: %s\r\n", strings.Join(to, ", "))
+	fmt.Fprintf(&msg, "Subject: %s\r\n", mime.QEncoding.Encode("utf-8", title))
+	fmt.Fprintf(&msg, "Date: %s\r\n", ev.Time.Format(time.RFC1123Z))
+	// The same ID on every retry lets servers drop duplicates.
+	fmt.Fprintf(&msg, "Message-ID: <%s.%s@pricegoldtoday>\r\n", ev.ID, n.name)
+	msg.WriteString("MIME-Version: 1.0\r\n")
+	fmt.Fprintf(&msg, "Content-Type: multipart/alternative; boundary=%q\r\n\r\n", mw.Boundary())
+	msg.Write(body.Bytes())
+	return msg.Bytes(), nil
+}
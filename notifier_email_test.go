@@ -0,0 +1,136 @@
+package main
+
+import (
+	"bytes"
+	"io"
+	"mime"
+	"mime/multipart"
+	"net/mail"
+	"strings"
+	"testing"
+)
+
+// emailParts parses msg and returns its decoded subject and the content of
+// each part by content type.
+func emailParts(t *testing.T, msg []byte) (header mail.Header, subject string, parts map[string]string) {
+	t.Helper()
+	m, err := mail.ReadMessage(bytes.NewReader(msg))
+	if err != nil {
+		t.Fatal(err)
+	}
+	subject, err = new(mime.WordDecoder).DecodeHeader(m.Header.Get("Subject"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	mediaType, params, err := mime.ParseMediaType(m.Header.Get("Content-Type"))
+	if err != nil || mediaType != "multipart/alternative" {
+		t.Fatalf("Content-Type %q: %v", m.Header.Get("Content-Type"), err)
+	}
+
+	parts = make(map[string]string)
+	mr := multipart.NewReader(m.Body, params["boundary"])
+	for {
+		p, err := mr.NextPart() // undoes the quoted-printable encoding
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		content, err := io.ReadAll(p)
+		if err != nil {
+			t.Fatal(err)
+		}
+		// Quoted-printable text travels with CRLF line breaks.
+		parts[p.Header.Get("Content-Type")] = strings.ReplaceAll(string(content), "\r\n", "\n")
+	}
+	return m.Header, subject, parts
+}
+
+func TestEmailNotifierMessage(t *testing.T) {
+	daily, _ := chatEvents()
+	weekly := newEvent(eventDigestWeekly)
+	weekly.Time = daily.Time
+	weekly.Digest = &DigestEvent{
+		CompareDays: 7,
+		From:        "2025-03-10",
+		To:          "2025-03-16",
+		Weekly: []WeekSummary{{
+			GoldType: "sjc",
+			Name:     "SJC",
+			From:     "2025-03-10",
+			To:       "2025-03-16",
+			Buy:      PriceRange{Open: 117e6, Close: 118.5e6, High: 119e6, Low: 116.5e6},
+			Sell:     PriceRange{Open: 119e6, Close: 121e6, High: 121.5e6, Low: 118.5e6},
+		}},
+	}
+
+	tests := []struct {
+		name     string
+		language string
+		unit     string
+		ev       Event
+		subject  string
+		text     []string
+		html     []string
+	}{
+		{
+			name: "daily", language: "vi", unit: "luong", ev: daily,
+			subject: "💰 Bảng giá vàng ngày 16/03",
+			text:    []string{"SJC\n  Mua vào: 118.5 ↑0.5 (0.4%)\n  Bán ra: 121.0 ↑1.0 (0.8%)", "Đơn vị: triệu đồng/lượng", "So sánh với ngày 15/03"},
+			html:    []string{"<td>SJC</td>", "<b>118.5</b>", "<th align=\"right\">Mua vào</th>"},
+		},
+		{
+			name: "daily in chi", language: "en", unit: "chi", ev: daily,
+			subject: "💰 Gold prices Mar 16",
+			text:    []string{"SJC\n  Buy: 11850 ↑50 (0.4%)\n  Sell: 12100 ↑100 (0.8%)", "Unit: thousand VND/chi"},
+			html:    []string{"<b>12100</b>", "Compared with Mar 15"},
+		},
+		{
+			name: "weekly", language: "en", unit: "luong", ev: weekly,
+			subject: "📅 Gold prices, week of Mar 10 – Mar 16",
+			text:    []string{"SJC\n  Buy: Open 117.0 → Close 118.5 ↑1.5 (1.3%), High 119.0, Low 116.5\n  Sell: Open 119.0 → Close 121.0 ↑2.0 (1.7%), High 121.5, Low 118.5"},
+			html:    []string{"<td>SJC</td><td>Buy</td>", "<b>121.0</b>", "<th align=\"right\">High</th>"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			n, err := newEmailNotifier(EmailNotifierConfig{
+				Name: "mail", Host: "smtp.example.com", Port: 587,
+				From: "Giá vàng <gold@example.com>", To: []string{"a@example.com", "b@example.com"},
+				Language: tt.language, Unit: tt.unit,
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+			msg, err := n.message(tt.ev)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			header, subject, parts := emailParts(t, msg)
+			if subject != tt.subject {
+				t.Errorf("subject %q, want %q", subject, tt.subject)
+			}
+			if to := header.Get("To"); to != "<a@example.com>, <b@example.com>" {
+				t.Errorf("To %q", to)
+			}
+			if id := header.Get("Message-ID"); id != "<"+tt.ev.ID+".mail@pricegoldtoday>" {
+				t.Errorf("Message-ID %q", id)
+			}
+			if len(parts) != 2 {
+				t.Fatalf("parts %v, want text and HTML", parts)
+			}
+			for _, want := range tt.text {
+				if text := parts["text/plain; charset=utf-8"]; !strings.Contains(text, want) {
+					t.Errorf("text part lacks %q:\n%s", want, text)
+				}
+			}
+			for _, want := range tt.html {
+				if html := parts["text/html; charset=utf-8"]; !strings.Contains(html, want) {
+					t.Errorf("HTML part lacks %q:\n%s", want, html)
+				}
+			}
+		})
+	}
+}
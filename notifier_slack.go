@@ -0,0 +1,92 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"strings"
+)
+
+type slackMessage struct {
+	// Text is the fallback shown in notifications.
+	Text   string       `json:"text"`
+	Blocks []slackBlock `json:"blocks"`
+}
+
+type slackBlock struct {
+	Type     string      `json:"type"`
+	Text     *slackText  `json:"text,omitempty"`
+	Fields   []slackText `json:"fields,omitempty"`
+	Elements []slackText `json:"elements,omitempty"`
+}
+
+type slackText struct {
+	Type string `json:"type"`
+	Text string `json:"text"`
+}
+
+func slackMrkdwn(text string) slackText {
+	return slackText{Type: "mrkdwn", Text: text}
+}
+
+// slackEscape escapes the characters Slack treats as markup.
+var slackEscape = strings.NewReplacer("&", "&amp;", "<", "&lt;", ">", "&gt;").Replace
+
+// slackNotifier posts the daily digest and alerts to a Slack incoming
+// webhook with Block Kit.
+type slackNotifier struct {
+	chatNotifier
+}
+
+func newSlackNotifier(cfg ChatNotifierConfig) *slackNotifier {
+	return &slackNotifier{newChatNotifier(cfg)}
+}
+
+func (n *slackNotifier) Notify(ctx context.Context, ev Event) error {
+	if !wantsEvent(n.events, ev.Type) {
+		return nil
+	}
+	switch {
+	case ev.Type == eventDigestDaily && ev.Digest != nil:
+		return n.post(ctx, n.digestMessage(ev))
+	case ev.Type == eventAlertFired && ev.Alert != nil:
+		title := labelsFor(n.opts.Locale).Alert
+		message, description := n.alert(ev)
+		return n.post(ctx, slackMessage{
+			Text: title + ": " + message,
+			Blocks: []slackBlock{
+				{Type: "header", Text: &slackText{Type: "plain_text", Text: title}},
+				{Type: "section", Text: &slackText{Type: "mrkdwn", Text: slackEscape(message)}},
+				{Type: "context", Elements: []slackText{slackMrkdwn(slackEscape(description))}},
+			},
+		})
+	}
+	return nil
+}
+
+func (n *slackNotifier) digestMessage(ev Event) slackMessage {
+	labels := labelsFor(n.opts.Locale)
+	digest := n.digest(ev)
+	title := fmt.Sprintf(labels.Title, digest.Date.Format(labels.DateFmt))
+
+	msg := slackMessage{
+		Text:   title,
+		Blocks: []slackBlock{{Type: "header", Text: &slackText{Type: "plain_text", Text: title}}},
+	}
+	// A section holds at most 10 fields.
+	var fields []slackText
+	for _, row := range digest.Rows {
+		fields = append(fields, slackMrkdwn(fmt.Sprintf("*%s*\n%s: `%s` %s\n%s: `%s` %s",
+			slackEscape(row.Name), labels.Buy, row.Buy, row.BuyChange, labels.Sell, row.Sell, row.SellChange)))
+	}
+	for len(fields) > 0 {
+		chunk := fields[:min(len(fields), 10)]
+		fields = fields[len(chunk):]
+		msg.Blocks = append(msg.Blocks, slackBlock{Type: "section", Fields: chunk})
+	}
+	msg.Blocks = append(msg.Blocks, slackBlock{Type: "context", Elements: []slackText{
+		slackMrkdwn(fmt.Sprintf(labels.Unit, digest.Unit)),
+		slackMrkdwn(fmt.Sprintf(labels.Compared, digest.PrevDate.Format(labels.DateFmt))),
+		slackMrkdwn(fmt.Sprintf(labels.Updated, digest.UpdatedAt.Format(labels.TimeFmt))),
+	}})
+	return msg
+}
@@ -0,0 +1,104 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"io"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// Headers sent with every webhook delivery.
+const (
+	webhookSignatureHeader = "X-Gold-Signature"
+	webhookTimestampHeader = "X-Gold-Timestamp"
+	webhookEventHeader     = "X-Gold-Event"
+	webhookDeliveryHeader  = "X-Gold-Delivery"
+)
+
+// webhookNotifier POSTs events as JSON to a URL. The body is signed with
+// HMAC-SHA256 over "<timestamp>.<body>" so the receiver can check both the
+// sender and the freshness of the request.
+type webhookNotifier struct {
+	name   string
+	url    string
+	secret []byte
+	events []string // empty means every event type
+	client *http.Client
+	retry  retryPolicy
+}
+
+func newWebhookNotifier(cfg WebhookNotifierConfig) *webhookNotifier {
+	return &webhookNotifier{
+		name:   cfg.Name,
+		url:    cfg.URL,
+		secret: []byte(cfg.Secret),
+		events: cfg.Events,
+		client: &http.Client{Timeout: cfg.Timeout.Duration},
+		retry:  defaultRetryPolicy,
+	}
+}
+
+func (n *webhookNotifier) Name() string {
+	return n.name
+}
+
+func (n *webhookNotifier) Notify(ctx context.Context, ev Event) error {
+	if !wantsEvent(n.events, ev.Type) {
+		return nil
+	}
+	body, err := json.Marshal(ev)
+	if err != nil {
+		return err
+	}
+	return n.retry.Do(ctx, func(ctx context.Context) error {
+		// Sign each attempt with a fresh timestamp.
+		timestamp := strconv.FormatInt(time.Now().Unix(), 10)
+		return postJSON(ctx, n.client, n.url, body, map[string]string{
+			webhookTimestampHeader: timestamp,
+			webhookSignatureHeader: "sha256=" + signWebhook(n.secret, timestamp, body),
+			webhookEventHeader:     ev.Type,
+			webhookDeliveryHeader:  ev.ID,
+		})
+	})
+}
+
+// postJSON sends one JSON POST; any status outside 2xx is an
+// *httpStatusError so that retryPolicy can classify it.
+func postJSON(ctx context.Context, client *http.Client, url string, body []byte, headers map[string]string) error {
+	req, err := http.NewRequestWithContext(ctx, "POST", url, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "pricegoldtoday-notifier/1")
+	for k, v := range headers {
+		req.Header.Set(k, v)
+	}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	io.Copy(io.Discard, io.LimitReader(resp.Body, 1<<16))
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return newHTTPStatusError(resp)
+	}
+	return nil
+}
+
+// signWebhook returns the hex HMAC-SHA256 of "<timestamp>.<body>".
+func signWebhook(secret []byte, timestamp string, body []byte) string {
+	mac := hmac.New(sha256.New, secret)
+	mac.Write([]byte(timestamp))
+	mac.Write([]byte("."))
+	mac.Write(body)
+	return hex.EncodeToString(mac.Sum(nil))
+}
@@ -0,0 +1,152 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"crypto/hmac"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strconv"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// fastRetry keeps the notifier tests from waiting out real backoff.
+var fastRetry = retryPolicy{MaxAttempts: 3, BaseDelay: time.Millisecond, MaxDelay: time.Millisecond}
+
+func TestWebhookNotifierSignsDeliveries(t *testing.T) {
+	const secret = "s3cret"
+	var got struct {
+		header http.Header
+		body   []byte
+	}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		got.header = r.Header.Clone()
+		got.body, _ = io.ReadAll(r.Body)
+	}))
+	defer srv.Close()
+
+	n := newWebhookNotifier(WebhookNotifierConfig{Name: "test", URL: srv.URL, Secret: secret, Timeout: Duration{time.Second}})
+	ev := newEvent(eventPriceUpdated)
+	ev.Price = &PriceEvent{GoldType: "sjc", Buy: 118e6, Sell: 120e6}
+	if err := n.Notify(context.Background(), ev); err != nil {
+		t.Fatal(err)
+	}
+
+	timestamp := got.header.Get(webhookTimestampHeader)
+	if ts, err := strconv.ParseInt(timestamp, 10, 64); err != nil || time.Since(time.Unix(ts, 0)) > time.Minute {
+		t.Errorf("timestamp header %q is not the current Unix time", timestamp)
+	}
+	want := "sha256=" + signWebhook([]byte(secret), timestamp, got.body)
+	if sig := got.header.Get(webhookSignatureHeader); !hmac.Equal([]byte(sig), []byte(want)) {
+		t.Errorf("signature %q, want %q", sig, want)
+	}
+	if sig := got.header.Get(webhookSignatureHeader); sig == "sha256="+signWebhook([]byte("other"), timestamp, got.body) {
+		t.Error("signature does not depend on the secret")
+	}
+	if got.header.Get(webhookEventHeader) != eventPriceUpdated || got.header.Get(webhookDeliveryHeader) != ev.ID {
+		t.Errorf("event headers = %q, %q", got.header.Get(webhookEventHeader), got.header.Get(webhookDeliveryHeader))
+	}
+	if ct := got.header.Get("Content-Type"); ct != "application/json" {
+		t.Errorf("Content-Type %q", ct)
+	}
+
+	var sent Event
+	if err := json.Unmarshal(got.body, &sent); err != nil {
+		t.Fatal(err)
+	}
+	if sent.ID != ev.ID || sent.Version != eventVersion || sent.Price == nil || sent.Price.Sell != 120e6 {
+		t.Errorf("body = %s", got.body)
+	}
+}
+
+func TestWebhookNotifierDelivery(t *testing.T) {
+	tests := []struct {
+		name      string
+		events    []string
+		statuses  []int
+		wantCalls int32
+		wantErr   bool
+	}{
+		{name: "delivered", statuses: []int{http.StatusNoContent}, wantCalls: 1},
+		{name: "filtered out", events: []string{eventAlertFired}, wantCalls: 0},
+		{name: "retried after a server error", statuses: []int{http.StatusBadGateway, http.StatusOK}, wantCalls: 2},
+		{name: "gives up", statuses: []int{500, 500, 500}, wantCalls: 3, wantErr: true},
+		{name: "client error is not retried", statuses: []int{http.StatusGone}, wantCalls: 1, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var calls atomic.Int32
+			srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				i := calls.Add(1) - 1
+				w.WriteHeader(tt.statuses[min(int(i), len(tt.statuses)-1)])
+			}))
+			defer srv.Close()
+
+			n := newWebhookNotifier(WebhookNotifierConfig{Name: "test", URL: srv.URL, Secret: "x", Events: tt.events, Timeout: Duration{time.Second}})
+			n.retry = fastRetry
+			err := n.Notify(context.Background(), newEvent(eventPriceUpdated))
+			if (err != nil) != tt.wantErr {
+				t.Errorf("Notify = %v, want error %t", err, tt.wantErr)
+			}
+			if got := calls.Load(); got != tt.wantCalls {
+				t.Errorf("%d requests, want %d", got, tt.wantCalls)
+			}
+		})
+	}
+}
+
+type failingNotifier struct{ err error }
+
+func (n failingNotifier) Name() string                               { return "broken" }
+func (n failingNotifier) Notify(ctx context.Context, ev Event) error { return n.err }
+
+func TestNotifierHubDeadLetters(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "dead", "letters.jsonl")
+	hub := NewNotifierHub([]Notifier{failingNotifier{errors.New("connection refused")}}, time.Second, path)
+
+	first, second := newEvent(eventPriceUpdated), newEvent(eventAlertFired)
+	hub.Publish(first)
+	waitForLines(t, path, 1)
+	hub.Publish(second)
+	letters := waitForLines(t, path, 2)
+
+	for i, want := range []Event{first, second} {
+		var got deadLetter
+		if err := json.Unmarshal([]byte(letters[i]), &got); err != nil {
+			t.Fatalf("line %d: %v", i+1, err)
+		}
+		if got.Notifier != "broken" || got.Error != "connection refused" || got.Event.ID != want.ID || got.Event.Type != want.Type {
+			t.Errorf("line %d = %+v, want %s event %s", i+1, got, want.Type, want.ID)
+		}
+	}
+}
+
+// waitForLines polls path until it holds n lines and returns them.
+func waitForLines(t *testing.T, path string, n int) []string {
+	t.Helper()
+	deadline := time.Now().Add(2 * time.Second)
+	for {
+		var lines []string
+		if f, err := os.Open(path); err == nil {
+			sc := bufio.NewScanner(f)
+			for sc.Scan() {
+				lines = append(lines, sc.Text())
+			}
+			f.Close()
+		}
+		if len(lines) >= n {
+			return lines
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("%s has %d lines, want %d", path, len(lines), n)
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}
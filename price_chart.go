@@ -0,0 +1,120 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	bottelegram "pricegoldtoday/bot"
+	"pricegoldtoday/chart"
+	"pricegoldtoday/vntime"
+)
+
+const (
+	defaultChartDays = 30
+	maxChartSize     = 2000
+)
+
+// renderPriceChart draws the stored history of goldTypes between from and
+// to as a PNG. It returns chart.ErrNoData when there is nothing to draw.
+func renderPriceChart(ctx context.Context, goldTypes []string, query seriesQuery, opts chart.Options) ([]byte, error) {
+	var series []chart.Series
+	for _, goldType := range goldTypes {
+		points, err := store.History(ctx, goldType, query.From, query.To)
+		if err != nil {
+			return nil, err
+		}
+		provider, _ := findProvider(goldType)
+		s := chart.Series{Name: provider.Name}
+		for _, p := range query.Apply(points) {
+			date, err := time.ParseInLocation(historyDateLayout, p.Date, vntime.Location)
+			if err != nil {
+				continue
+			}
+			s.Points = append(s.Points, chart.Point{Date: date, Buy: p.Buy, Sell: p.Sell})
+		}
+		series = append(series, s)
+	}
+
+	if opts.Title == "" {
+		opts.Title = "Giá vàng (triệu đồng/lượng)"
+	}
+	if opts.Scale == 0 {
+		opts.Scale = 1e6
+	}
+
+	var buf bytes.Buffer
+	if err := chart.Render(&buf, series, opts); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// chatChartOptions titles and scales a chart in a chat's language and unit.
+func chatChartOptions(opts bottelegram.DigestOptions) chart.Options {
+	title := fmt.Sprintf(labelsFor(opts.Locale).ChartTitle, bottelegram.UnitLabel(opts.Unit, opts.Locale))
+	return chart.Options{Title: title, Scale: bottelegram.UnitScale(opts.Unit)}
+}
+
+// recentDays is the query for the last n days up to today.
+func recentDays(n int) seriesQuery {
+	today := vntime.Today()
+	return seriesQuery{From: today.AddDate(0, 0, -(n - 1)), To: today}
+}
+
+// getChartHandler serves GET /api/chart.png?types=sjc,doji_hn with the same
+// range parameters as the history endpoint, plus width and height.
+func getChartHandler(w http.ResponseWriter, r *http.Request) {
+	values := r.URL.Query()
+
+	goldTypes := []string{defaultGoldType}
+	if v := values.Get("types"); v != "" {
+		goldTypes = splitList(v)
+	}
+	for _, goldType := range goldTypes {
+		if _, ok := enabledProvider(goldType); !ok {
+			respondWithError(w, http.StatusBadRequest, fmt.Sprintf("Unknown gold type %q", goldType))
+			return
+		}
+	}
+
+	query, err := parseSeriesQuery(values, vntime.Now())
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	if !query.IsSet() {
+		query = recentDays(defaultChartDays)
+	}
+
+	var opts chart.Options
+	for name, dst := range map[string]*int{"width": &opts.Width, "height": &opts.Height} {
+		if v := values.Get(name); v != "" {
+			n, err := strconv.Atoi(v)
+			if err != nil || n < 200 || n > maxChartSize {
+				respondWithError(w, http.StatusBadRequest, fmt.Sprintf("'%s' must be an integer between 200 and %d", name, maxChartSize))
+				return
+			}
+			*dst = n
+		}
+	}
+
+	img, err := renderPriceChart(r.Context(), goldTypes, query, opts)
+	if errors.Is(err, chart.ErrNoData) {
+		respondWithError(w, http.StatusNotFound, "No history for the requested range")
+		return
+	}
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, fmt.Sprintf("Failed to render chart: %v", err))
+		return
+	}
+
+	w.Header().Set("Content-Type", "image/png")
+	w.Header().Set("Cache-Control", "public, max-age=300")
+	w.WriteHeader(http.StatusOK)
+	w.Write(img)
+}
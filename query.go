@@ -0,0 +1,126 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+	"strconv"
+	"time"
+
+	"pricegoldtoday/vntime"
+)
+
+const (
+	maxQueryDays  = 3650
+	maxQueryLimit = 10000
+
+	// maxV1QueryDays bounds v1 ranges, whose "dd/mm" categories carry no
+	// year and would repeat over a longer range.
+	maxV1QueryDays = 366
+)
+
+// seriesQuery is the date window requested through the from, to, days and
+// limit query parameters.
+type seriesQuery struct {
+	From  time.Time
+	To    time.Time
+	Limit int // keep only the most recent Limit points; 0 means all
+}
+
+// IsSet reports whether the request asked for any slicing at all.
+func (q seriesQuery) IsSet() bool {
+	return !q.From.IsZero() || !q.To.IsZero() || q.Limit > 0
+}
+
+// Apply trims points (oldest first) to the query's limit.
+func (q seriesQuery) Apply(points []PricePoint) []PricePoint {
+	if q.Limit > 0 && len(points) > q.Limit {
+		return points[len(points)-q.Limit:]
+	}
+	return points
+}
+
+// parseSeriesQuery validates the range parameters. Dates are calendar days
+// in Vietnam; days counts back from today and cannot be combined with from.
+func parseSeriesQuery(values url.Values, now time.Time) (seriesQuery, error) {
+	var q seriesQuery
+	var err error
+
+	if v := values.Get("from"); v != "" {
+		if q.From, err = time.ParseInLocation(historyDateLayout, v, vntime.Location); err != nil {
+			return q, errors.New("invalid 'from' date, expected YYYY-MM-DD")
+		}
+	}
+	if v := values.Get("to"); v != "" {
+		if q.To, err = time.ParseInLocation(historyDateLayout, v, vntime.Location); err != nil {
+			return q, errors.New("invalid 'to' date, expected YYYY-MM-DD")
+		}
+	}
+	if v := values.Get("days"); v != "" {
+		days, err := strconv.Atoi(v)
+		if err != nil || days < 1 || days > maxQueryDays {
+			return q, fmt.Errorf("'days' must be an integer between 1 and %d", maxQueryDays)
+		}
+		if !q.From.IsZero() {
+			return q, errors.New("'days' cannot be combined with 'from'")
+		}
+		end := vntime.Day(now)
+		if !q.To.IsZero() {
+			end = q.To
+		}
+		q.From = end.AddDate(0, 0, -(days - 1))
+	}
+	if v := values.Get("limit"); v != "" {
+		limit, err := strconv.Atoi(v)
+		if err != nil || limit < 1 || limit > maxQueryLimit {
+			return q, fmt.Errorf("'limit' must be an integer between 1 and %d", maxQueryLimit)
+		}
+		q.Limit = limit
+	}
+	if !q.From.IsZero() && !q.To.IsZero() && q.From.After(q.To) {
+		return q, errors.New("'from' must not be after 'to'")
+	}
+	return q, nil
+}
+
+// v1Range checks that q spans at most maxV1QueryDays and closes an open
+// start at that many days before the end, so v1 categories stay
+// unambiguous. Longer ranges are served by the v2 API.
+func (q seriesQuery) v1Range(now time.Time) (seriesQuery, error) {
+	end := vntime.Day(now)
+	if !q.To.IsZero() {
+		end = q.To
+	}
+	earliest := end.AddDate(0, 0, -(maxV1QueryDays - 1))
+	if q.From.IsZero() {
+		q.From = earliest
+		return q, nil
+	}
+	if q.From.Before(earliest) {
+		return q, fmt.Errorf("ranges longer than %d days are only available from /api/v2/gold-price/{type}", maxV1QueryDays)
+	}
+	return q, nil
+}
+
+// goldPriceFromHistory rebuilds a v1 GoldPrice from history points, keeping
+// the metadata of the latest snapshot.
+func goldPriceFromHistory(snapshot *GoldPrice, points []PricePoint) *GoldPrice {
+	res := &GoldPrice{
+		Type:       snapshot.Type,
+		Dates:      make([]string, 0, len(points)),
+		BuyPrices:  make([]float64, 0, len(points)),
+		SellPrices: make([]float64, 0, len(points)),
+		UpdatedAt:  snapshot.UpdatedAt,
+		Source:     snapshot.Source,
+	}
+	for _, p := range points {
+		date, err := time.ParseInLocation(historyDateLayout, p.Date, vntime.Location)
+		if err != nil {
+			continue
+		}
+		res.Dates = append(res.Dates, vntime.Category(date))
+		res.BuyPrices = append(res.BuyPrices, p.Buy)
+		res.SellPrices = append(res.SellPrices, p.Sell)
+	}
+	return res
+}
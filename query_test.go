@@ -0,0 +1,115 @@
+package main
+
+import (
+	"net/url"
+	"testing"
+	"time"
+
+	"pricegoldtoday/vntime"
+)
+
+func TestParseSeriesQuery(t *testing.T) {
+	now := time.Date(2025, 3, 16, 9, 30, 0, 0, vntime.Location)
+
+	tests := []struct {
+		query   string
+		from    string
+		to      string
+		limit   int
+		wantErr bool
+	}{
+		{query: ""},
+		{query: "from=2025-01-01&to=2025-01-31", from: "2025-01-01", to: "2025-01-31"},
+		{query: "days=7", from: "2025-03-10"},
+		{query: "days=1", from: "2025-03-16"},
+		{query: "days=30&to=2025-02-28", from: "2025-01-30", to: "2025-02-28"},
+		{query: "limit=5", limit: 5},
+		{query: "from=2025-03-16&to=2025-03-16", from: "2025-03-16", to: "2025-03-16"},
+		{query: "from=16/03/2025", wantErr: true},
+		{query: "to=2025-02-30", wantErr: true},
+		{query: "days=0", wantErr: true},
+		{query: "days=3651", wantErr: true},
+		{query: "days=7&from=2025-01-01", wantErr: true},
+		{query: "limit=0", wantErr: true},
+		{query: "limit=abc", wantErr: true},
+		{query: "from=2025-02-01&to=2025-01-01", wantErr: true},
+	}
+	for _, tt := range tests {
+		values, err := url.ParseQuery(tt.query)
+		if err != nil {
+			t.Fatal(err)
+		}
+		q, err := parseSeriesQuery(values, now)
+		if tt.wantErr {
+			if err == nil {
+				t.Errorf("parseSeriesQuery(%q) = %+v, want an error", tt.query, q)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("parseSeriesQuery(%q): %v", tt.query, err)
+			continue
+		}
+		if got := formatQueryDate(q.From); got != tt.from {
+			t.Errorf("parseSeriesQuery(%q).From = %q, want %q", tt.query, got, tt.from)
+		}
+		if got := formatQueryDate(q.To); got != tt.to {
+			t.Errorf("parseSeriesQuery(%q).To = %q, want %q", tt.query, got, tt.to)
+		}
+		if q.Limit != tt.limit {
+			t.Errorf("parseSeriesQuery(%q).Limit = %d, want %d", tt.query, q.Limit, tt.limit)
+		}
+		if q.IsSet() != (tt.query != "") {
+			t.Errorf("parseSeriesQuery(%q).IsSet() = %t", tt.query, q.IsSet())
+		}
+	}
+}
+
+func TestV1Range(t *testing.T) {
+	now := time.Date(2025, 3, 16, 9, 30, 0, 0, vntime.Location)
+
+	tests := []struct {
+		query   string
+		from    string
+		wantErr bool
+	}{
+		{query: "", from: "2024-03-16"},
+		{query: "days=30", from: "2025-02-15"},
+		{query: "days=366", from: "2024-03-16"},
+		{query: "to=2024-12-31", from: "2024-01-01"},
+		{query: "from=2024-03-16", from: "2024-03-16"},
+		{query: "days=367", wantErr: true},
+		{query: "from=2023-01-01&to=2025-01-01", wantErr: true},
+	}
+	for _, tt := range tests {
+		values, err := url.ParseQuery(tt.query)
+		if err != nil {
+			t.Fatal(err)
+		}
+		q, err := parseSeriesQuery(values, now)
+		if err != nil {
+			t.Fatalf("parseSeriesQuery(%q): %v", tt.query, err)
+		}
+		q, err = q.v1Range(now)
+		if tt.wantErr {
+			if err == nil {
+				t.Errorf("v1Range(%q) = %+v, want an error", tt.query, q)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("v1Range(%q): %v", tt.query, err)
+			continue
+		}
+		if got := formatQueryDate(q.From); got != tt.from {
+			t.Errorf("v1Range(%q).From = %q, want %q", tt.query, got, tt.from)
+		}
+	}
+}
+
+func formatQueryDate(t time.Time) string {
+	if t.IsZero() {
+		return ""
+	}
+	return t.Format(historyDateLayout)
+}
@@ -0,0 +1,132 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"math/rand"
+	"net"
+	"net/http"
+	"net/textproto"
+	"strconv"
+	"time"
+)
+
+// httpStatusError is returned by sources when the upstream answers with a
+// non-200 status.
+type httpStatusError struct {
+	StatusCode int
+	RetryAfter time.Duration // zero when the upstream sent no Retry-After
+}
+
+func newHTTPStatusError(resp *http.Response) *httpStatusError {
+	return &httpStatusError{
+		StatusCode: resp.StatusCode,
+		RetryAfter: parseRetryAfter(resp.Header.Get("Retry-After"), time.Now()),
+	}
+}
+
+func (e *httpStatusError) Error() string {
+	return fmt.Sprintf("HTTP request returned status: %d", e.StatusCode)
+}
+
+// parseRetryAfter accepts both forms of the header: delay seconds and an
+// HTTP date.
+func parseRetryAfter(v string, now time.Time) time.Duration {
+	if v == "" {
+		return 0
+	}
+	if secs, err := strconv.Atoi(v); err == nil && secs > 0 {
+		return time.Duration(secs) * time.Second
+	}
+	if t, err := http.ParseTime(v); err == nil && t.After(now) {
+		return t.Sub(now)
+	}
+	return 0
+}
+
+// retryPolicy retries transient upstream failures with jittered exponential
+// backoff.
+type retryPolicy struct {
+	MaxAttempts int
+	BaseDelay   time.Duration
+	MaxDelay    time.Duration
+}
+
+var defaultRetryPolicy = retryPolicy{
+	MaxAttempts: 3,
+	BaseDelay:   500 * time.Millisecond,
+	MaxDelay:    10 * time.Second,
+}
+
+// Do calls fn until it succeeds, fails with a permanent error, runs out of
+// attempts or ctx is done. It returns the last error from fn.
+func (p retryPolicy) Do(ctx context.Context, fn func(ctx context.Context) error) error {
+	var err error
+	attempts := max(p.MaxAttempts, 1)
+	for attempt := 0; attempt < attempts; attempt++ {
+		if err = fn(ctx); err == nil {
+			return nil
+		}
+
+		retry, retryAfter := isRetryable(err)
+		if !retry || attempt == attempts-1 {
+			return err
+		}
+
+		wait := max(p.backoff(attempt), retryAfter)
+		if deadline, ok := ctx.Deadline(); ok && time.Until(deadline) < wait {
+			return err // waiting would outlive the caller anyway
+		}
+
+		timer := time.NewTimer(wait)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return err
+		case <-timer.C:
+		}
+	}
+	return err
+}
+
+// backoff returns a random delay in [0, min(MaxDelay, BaseDelay*2^attempt)).
+func (p retryPolicy) backoff(attempt int) time.Duration {
+	ceiling := p.BaseDelay << attempt
+	if ceiling <= 0 || ceiling > p.MaxDelay {
+		ceiling = p.MaxDelay
+	}
+	if ceiling <= 0 {
+		return 0
+	}
+	return time.Duration(rand.Int63n(int64(ceiling)))
+}
+
+// isRetryable reports whether err is worth another attempt: network errors,
+// 429 and 5xx responses and transient (4xx) SMTP replies. The second result
+// is the delay the upstream asked for.
+func isRetryable(err error) (bool, time.Duration) {
+	if errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+		return false, 0
+	}
+
+	var statusErr *httpStatusError
+	if errors.As(err, &statusErr) {
+		if statusErr.StatusCode == http.StatusTooManyRequests || statusErr.StatusCode >= 500 {
+			return true, statusErr.RetryAfter
+		}
+		return false, 0
+	}
+
+	var smtpErr *textproto.Error
+	if errors.As(err, &smtpErr) {
+		return smtpErr.Code >= 400 && smtpErr.Code < 500, 0
+	}
+
+	var netErr net.Error
+	if errors.As(err, &netErr) || errors.Is(err, io.ErrUnexpectedEOF) {
+		return true, 0
+	}
+	return false, 0
+}
@@ -0,0 +1,123 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/textproto"
+	"testing"
+	"time"
+)
+
+func TestRetryPolicy(t *testing.T) {
+	policy := retryPolicy{MaxAttempts: 3, BaseDelay: time.Millisecond, MaxDelay: 5 * time.Millisecond}
+	serverErr := &httpStatusError{StatusCode: 503}
+
+	tests := []struct {
+		name      string
+		errs      []error // returned by successive attempts; nil means success
+		wantCalls int
+		wantErr   bool
+	}{
+		{"first try", []error{nil}, 1, false},
+		{"recovers", []error{serverErr, serverErr, nil}, 3, false},
+		{"runs out of attempts", []error{serverErr, serverErr, serverErr, nil}, 3, true},
+		{"permanent error", []error{&httpStatusError{StatusCode: 404}, nil}, 1, true},
+		{"parse error", []error{errors.New("no prices found"), nil}, 1, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			calls := 0
+			err := policy.Do(context.Background(), func(context.Context) error {
+				err := tt.errs[calls]
+				calls++
+				return err
+			})
+			if calls != tt.wantCalls {
+				t.Errorf("got %d calls, want %d", calls, tt.wantCalls)
+			}
+			if (err != nil) != tt.wantErr {
+				t.Errorf("got error %v, want error %t", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestRetryPolicySingleAttempt(t *testing.T) {
+	for _, attempts := range []int{0, 1} {
+		policy := retryPolicy{MaxAttempts: attempts, BaseDelay: time.Hour, MaxDelay: time.Hour}
+		start := time.Now()
+		calls := 0
+		err := policy.Do(context.Background(), func(context.Context) error {
+			calls++
+			return &httpStatusError{StatusCode: 503}
+		})
+		if err == nil || calls != 1 {
+			t.Errorf("MaxAttempts %d: got %d calls and error %v, want one call and the 503", attempts, calls, err)
+		}
+		if elapsed := time.Since(start); elapsed > time.Second {
+			t.Errorf("MaxAttempts %d: took %v, want no backoff after the last attempt", attempts, elapsed)
+		}
+	}
+}
+
+func TestRetryPolicyHonoursDeadline(t *testing.T) {
+	policy := retryPolicy{MaxAttempts: 3, BaseDelay: time.Millisecond, MaxDelay: time.Millisecond}
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+
+	calls := 0
+	err := policy.Do(ctx, func(context.Context) error {
+		calls++
+		return &httpStatusError{StatusCode: 429, RetryAfter: time.Minute}
+	})
+	if err == nil || calls != 1 {
+		t.Errorf("got %d calls and error %v, want one call and the 429", calls, err)
+	}
+}
+
+func TestIsRetryable(t *testing.T) {
+	tests := []struct {
+		name      string
+		err       error
+		want      bool
+		wantAfter time.Duration
+	}{
+		{"server error", &httpStatusError{StatusCode: 500}, true, 0},
+		{"rate limited", &httpStatusError{StatusCode: 429, RetryAfter: 3 * time.Second}, true, 3 * time.Second},
+		{"forbidden", &httpStatusError{StatusCode: 403}, false, 0},
+		{"network error", &net.OpError{Op: "dial", Err: errors.New("connection refused")}, true, 0},
+		{"transient SMTP reply", &textproto.Error{Code: 421, Msg: "try again later"}, true, 0},
+		{"permanent SMTP reply", &textproto.Error{Code: 550, Msg: "mailbox unavailable"}, false, 0},
+		{"cancelled", context.Canceled, false, 0},
+		{"deadline", context.DeadlineExceeded, false, 0},
+	}
+	for _, tt := range tests {
+		got, after := isRetryable(tt.err)
+		if got != tt.want || after != tt.wantAfter {
+			t.Errorf("%s: isRetryable = %t, %v; want %t, %v", tt.name, got, after, tt.want, tt.wantAfter)
+		}
+	}
+}
+
+func TestParseRetryAfter(t *testing.T) {
+	now := time.Date(2025, 3, 16, 2, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		value string
+		want  time.Duration
+	}{
+		{"", 0},
+		{"120", 2 * time.Minute},
+		{"0", 0},
+		{"-5", 0},
+		{"Sun, 16 Mar 2025 02:00:30 GMT", 30 * time.Second},
+		{"Sun, 16 Mar 2025 01:59:00 GMT", 0},
+		{"soon", 0},
+	}
+	for _, tt := range tests {
+		if got := parseRetryAfter(tt.value, now); got != tt.want {
+			t.Errorf("parseRetryAfter(%q) = %v, want %v", tt.value, got, tt.want)
+		}
+	}
+}
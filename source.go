@@ -0,0 +1,178 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// Source is an upstream provider of gold price charts.
+type Source interface {
+	// Name identifies the source in logs and responses.
+	Name() string
+	// GoldTypes lists the gold types this source can fetch.
+	GoldTypes() []string
+	// Fetch retrieves the current price series for a gold type.
+	Fetch(ctx context.Context, goldType string) (*GoldPrice, error)
+}
+
+var errNoSource = errors.New("no source supports this gold type")
+
+const (
+	breakerThreshold = 5
+	breakerCooldown  = 5 * time.Minute
+)
+
+// SourceDownHook is called when the circuit breaker of a source opens, with
+// the error of the call that opened it.
+type SourceDownHook func(source string, status BreakerStatus, err error)
+
+// SourceRegistry holds the configured sources in priority order. Every
+// source gets its own circuit breaker, and transient failures are retried
+// with the registry's retry policy.
+type SourceRegistry struct {
+	mu        sync.RWMutex
+	sources   []Source
+	breakers  map[string]*CircuitBreaker
+	retry     retryPolicy
+	downHooks []SourceDownHook
+}
+
+func NewSourceRegistry(sources ...Source) *SourceRegistry {
+	r := &SourceRegistry{
+		breakers: make(map[string]*CircuitBreaker),
+		retry:    defaultRetryPolicy,
+	}
+	for _, s := range sources {
+		r.Register(s)
+	}
+	return r
+}
+
+// Register appends a source; earlier sources are tried first.
+func (r *SourceRegistry) Register(s Source) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.sources = append(r.sources, s)
+	r.breakers[s.Name()] = NewCircuitBreaker(breakerThreshold, breakerCooldown)
+}
+
+// SourcesFor returns the sources that support goldType, in priority order.
+func (r *SourceRegistry) SourcesFor(goldType string) []Source {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	var res []Source
+	for _, s := range r.sources {
+		for _, t := range s.GoldTypes() {
+			if t == goldType {
+				res = append(res, s)
+				break
+			}
+		}
+	}
+	return res
+}
+
+// Fetch tries every source supporting goldType until one succeeds.
+func (r *SourceRegistry) Fetch(ctx context.Context, goldType string) (*GoldPrice, error) {
+	candidates := r.SourcesFor(goldType)
+	if len(candidates) == 0 {
+		return nil, fmt.Errorf("%w: %s", errNoSource, goldType)
+	}
+
+	var errs []error
+	for _, s := range candidates {
+		goldPrice, err := r.fetchFrom(ctx, s, goldType)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", s.Name(), err))
+			continue
+		}
+		goldPrice.Source = s.Name()
+		return goldPrice, nil
+	}
+	return nil, errors.Join(errs...)
+}
+
+func (r *SourceRegistry) fetchFrom(ctx context.Context, s Source, goldType string) (*GoldPrice, error) {
+	r.mu.RLock()
+	breaker := r.breakers[s.Name()]
+	r.mu.RUnlock()
+
+	if !breaker.Allow() {
+		return nil, errCircuitOpen
+	}
+
+	var goldPrice *GoldPrice
+	err := r.retry.Do(ctx, func(ctx context.Context) error {
+		var err error
+		goldPrice, err = s.Fetch(ctx, goldType)
+		return err
+	})
+	switch {
+	case err == nil:
+		breaker.Success()
+	case !isUpstreamFailure(ctx, err):
+		// The source answered, or the caller cancelled; either way this says
+		// nothing about the other gold types it serves.
+		breaker.Abort()
+	default:
+		if breaker.Failure() {
+			r.runDownHooks(s.Name(), breaker.Status(), err)
+		}
+	}
+	return goldPrice, err
+}
+
+// isUpstreamFailure reports whether err means the source itself is failing:
+// a network error or timeout, or a 429 or 5xx response. Parse errors, other
+// 4xx responses and the caller cancelling are not held against it, but a
+// deadline running out mid-fetch is: that is how a hanging source fails.
+func isUpstreamFailure(ctx context.Context, err error) bool {
+	if errors.Is(ctx.Err(), context.Canceled) {
+		return false
+	}
+	if errors.Is(err, context.DeadlineExceeded) {
+		return true
+	}
+	var statusErr *httpStatusError
+	if errors.As(err, &statusErr) {
+		return statusErr.StatusCode == http.StatusTooManyRequests || statusErr.StatusCode >= 500
+	}
+	var netErr net.Error
+	return errors.As(err, &netErr) || errors.Is(err, io.ErrUnexpectedEOF)
+}
+
+// OnDown registers hook to run when a source's circuit breaker opens.
+func (r *SourceRegistry) OnDown(hook SourceDownHook) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.downHooks = append(r.downHooks, hook)
+}
+
+func (r *SourceRegistry) runDownHooks(source string, status BreakerStatus, err error) {
+	r.mu.RLock()
+	hooks := r.downHooks
+	r.mu.RUnlock()
+
+	for _, hook := range hooks {
+		hook(source, status, err)
+	}
+}
+
+// Status returns the circuit breaker state of every source by name.
+func (r *SourceRegistry) Status() map[string]BreakerStatus {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	status := make(map[string]BreakerStatus, len(r.breakers))
+	for name, b := range r.breakers {
+		status[name] = b.Status()
+	}
+	return status
+}
@@ -0,0 +1,188 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/PuerkitoBio/goquery"
+)
+
+const twentyFourHURL = "https://24h.24hstatic.com/ajax/box_bieu_do_gia_vang/index/%s/0/0?is_template_page=1"
+
+// twentyFourHSource scrapes the Highcharts box embedded in 24h.com.vn.
+type twentyFourHSource struct {
+	goldTypes []string
+	client    *http.Client
+}
+
+func newTwentyFourHSource(goldTypes []string) *twentyFourHSource {
+	return &twentyFourHSource{
+		goldTypes: goldTypes,
+		client: &http.Client{
+			Timeout: 10 * time.Second,
+		},
+	}
+}
+
+func (s *twentyFourHSource) Name() string {
+	return "24h"
+}
+
+func (s *twentyFourHSource) GoldTypes() []string {
+	return s.goldTypes
+}
+
+func (s *twentyFourHSource) Fetch(ctx context.Context, goldType string) (*GoldPrice, error) {
+	url := fmt.Sprintf(twentyFourHURL, goldType)
+
+	// Tạo HTTP request với các headers cần thiết
+	req, err := http.NewRequestWithContext(ctx, "GET", url, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+
+	// Thêm các headers theo yêu cầu của trang web
+	req.Header.Add("accept", "*/*")
+	req.Header.Add("accept-language", "vi-VN,vi;q=0.9,en-GB;q=0.8,en;q=0.7,ko-KR;q=0.6,ko;q=0.5,fr-FR;q=0.4,fr;q=0.3,en-US;q=0.2")
+	req.Header.Add("origin", "https://www.24h.com.vn")
+	req.Header.Add("priority", "u=1, i")
+	req.Header.Add("referer", "https://www.24h.com.vn/")
+	req.Header.Add("sec-ch-ua", `"Google Chrome";v="137", "Chromium";v="137", "Not/A)Brand";v="24"`)
+	req.Header.Add("sec-ch-ua-mobile", "?0")
+	req.Header.Add("sec-ch-ua-platform", `"macOS"`)
+	req.Header.Add("sec-fetch-dest", "empty")
+	req.Header.Add("sec-fetch-mode", "cors")
+	req.Header.Add("sec-fetch-site", "cross-site")
+	req.Header.Add("user-agent", "Mozilla/5.0 (Macintosh; Intel Mac OS X 10_15_7) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/137.0.0.0 Safari/537.36")
+
+	// Gửi request
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("HTTP request failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, newHTTPStatusError(resp)
+	}
+
+	// Đọc response body
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read response body: %w", err)
+	}
+	chartData, err := extractChartData(string(body))
+	if err != nil {
+		return nil, fmt.Errorf("failed to extract chart data: %w", err)
+	}
+	var buyPrices []float64
+	var sellPrices []float64
+	for _, series := range chartData.Series {
+		if series.Name == "Mua vào" {
+			buyPrices = series.Data
+		} else if series.Name == "Bán ra" {
+			sellPrices = series.Data
+		}
+	}
+	return &GoldPrice{
+		Type:       goldType,
+		Dates:      chartData.Categories,
+		BuyPrices:  buyPrices,
+		SellPrices: sellPrices,
+		UpdatedAt:  time.Now(),
+	}, nil
+}
+
+type Series struct {
+	Name  string
+	Color string
+	Data  []float64
+}
+
+type ChartData struct {
+	Categories []string
+	Series     []Series
+}
+
+func extractChartData(html string) (*ChartData, error) {
+	doc, err := goquery.NewDocumentFromReader(strings.NewReader(html))
+	if err != nil {
+		return nil, err
+	}
+
+	var scriptContent string
+	doc.Find("script").Each(func(i int, s *goquery.Selection) {
+		text := s.Text()
+		if strings.Contains(text, "highcharts") && strings.Contains(text, "categories") {
+			scriptContent = text
+		}
+	})
+
+	if scriptContent == "" {
+		return nil, fmt.Errorf("script chứa highcharts không được tìm thấy")
+	}
+
+	// Parse categories
+	catRegex := regexp.MustCompile(`categories:\s*\[(.*?)\]`)
+	catMatch := catRegex.FindStringSubmatch(scriptContent)
+	if len(catMatch) < 2 {
+		return nil, fmt.Errorf("không tìm thấy categories")
+	}
+	categoriesRaw := catMatch[1]
+	categories := parseStringArray(categoriesRaw)
+
+	// Parse series
+	seriesRegex := regexp.MustCompile(`name:\s*'(.*?)',\s*color:\s*'(.*?)',\s*data:\s*\[(.*?)\]`)
+	seriesMatches := seriesRegex.FindAllStringSubmatch(scriptContent, -1)
+
+	var seriesList []Series
+	for _, match := range seriesMatches {
+		name := match[1]
+		color := match[2]
+		dataRaw := match[3]
+		data := parseFloat64Array(dataRaw)
+		seriesList = append(seriesList, Series{
+			Name:  name,
+			Color: color,
+			Data:  data,
+		})
+	}
+
+	return &ChartData{
+		Categories: categories,
+		Series:     seriesList,
+	}, nil
+}
+
+func parseStringArray(input string) []string {
+	rawItems := strings.Split(input, ",")
+	var items []string
+	for _, item := range rawItems {
+		item = strings.TrimSpace(item)
+		item = strings.Trim(item, "'\"")
+		items = append(items, item)
+	}
+	return items
+}
+
+func parseFloat64Array(input string) []float64 {
+	rawItems := strings.Split(input, ",")
+	var items []float64
+	for _, item := range rawItems {
+		var v float64
+		_, err := fmt.Sscanf(strings.TrimSpace(item), "%f", &v)
+		if err != nil {
+			// Nếu có lỗi, có thể log và bỏ qua hoặc gán giá trị mặc định
+			log.Printf("Error parsing float value '%s': %v", item, err)
+			v = 0.0
+		}
+		items = append(items, v)
+	}
+	return items
+}
@@ -0,0 +1,63 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// errNotFound is returned by a Store when the requested record is missing.
+var errNotFound = errors.New("not found")
+
+// Store persists price snapshots and the long-term price history.
+type Store interface {
+	SaveGoldPrice(ctx context.Context, goldType string, goldPrice *GoldPrice) error
+	GetGoldPrice(ctx context.Context, goldType string) (*GoldPrice, error)
+
+	// MergeHistory upserts points by date and reports how many were new
+	// and how many corrected an existing date.
+	MergeHistory(ctx context.Context, goldType string, points []PricePoint) (added, updated int, err error)
+	// History returns points between from and to (inclusive), oldest first.
+	// A zero from or to leaves that end of the range open.
+	History(ctx context.Context, goldType string, from, to time.Time) ([]PricePoint, error)
+
+	// SaveSubscriber creates or replaces the subscriber with the same chat.
+	SaveSubscriber(ctx context.Context, sub *Subscriber) error
+	// DeleteSubscriber removes a chat; removing an unknown chat is not an error.
+	DeleteSubscriber(ctx context.Context, chatID string) error
+	ListSubscribers(ctx context.Context) ([]*Subscriber, error)
+
+	// SaveAlert creates or replaces the rule with the same ID.
+	SaveAlert(ctx context.Context, rule *AlertRule) error
+	// DeleteAlert removes a rule; removing an unknown rule is not an error.
+	DeleteAlert(ctx context.Context, id string) error
+	// ListAlerts returns every rule, oldest first.
+	ListAlerts(ctx context.Context) ([]*AlertRule, error)
+
+	Close() error
+}
+
+func openStore(cfg StoreConfig) (Store, error) {
+	switch cfg.Backend {
+	case "", "redis":
+		return newRedisStore(cfg.Redis.Addr, cfg.Redis.Password, cfg.Redis.DB)
+	case "file":
+		return newFileStore(cfg.Path)
+	case "memory":
+		return newMemoryStore(), nil
+	default:
+		return nil, fmt.Errorf("unknown store backend %q", cfg.Backend)
+	}
+}
+
+// inHistoryRange reports whether a YYYY-MM-DD date lies within [from, to].
+func inHistoryRange(date string, from, to time.Time) bool {
+	if !from.IsZero() && date < from.Format(historyDateLayout) {
+		return false
+	}
+	if !to.IsZero() && date > to.Format(historyDateLayout) {
+		return false
+	}
+	return true
+}
@@ -0,0 +1,120 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+)
+
+// fileStore is a memoryStore that writes its whole state to a JSON file after
+// every change. It suits single-box deployments without Redis.
+type fileStore struct {
+	*memoryStore
+	path    string
+	flushMu sync.Mutex
+}
+
+func newFileStore(path string) (*fileStore, error) {
+	if path == "" {
+		return nil, errors.New("file store requires a path")
+	}
+	s := &fileStore{memoryStore: newMemoryStore(), path: path}
+
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return s, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(data, &s.state); err != nil {
+		return nil, fmt.Errorf("failed to parse %s: %w", path, err)
+	}
+	if s.state.Prices == nil {
+		s.state.Prices = make(map[string]*GoldPrice)
+	}
+	if s.state.History == nil {
+		s.state.History = make(map[string]map[string]PricePoint)
+	}
+	if s.state.Subscribers == nil {
+		s.state.Subscribers = make(map[string]*Subscriber)
+	}
+	if s.state.Alerts == nil {
+		s.state.Alerts = make(map[string]*AlertRule)
+	}
+	return s, nil
+}
+
+func (s *fileStore) SaveGoldPrice(ctx context.Context, goldType string, goldPrice *GoldPrice) error {
+	if err := s.memoryStore.SaveGoldPrice(ctx, goldType, goldPrice); err != nil {
+		return err
+	}
+	return s.flush()
+}
+
+func (s *fileStore) MergeHistory(ctx context.Context, goldType string, points []PricePoint) (added, updated int, err error) {
+	added, updated, err = s.memoryStore.MergeHistory(ctx, goldType, points)
+	if err != nil || added+updated == 0 {
+		return added, updated, err
+	}
+	return added, updated, s.flush()
+}
+
+func (s *fileStore) SaveSubscriber(ctx context.Context, sub *Subscriber) error {
+	if err := s.memoryStore.SaveSubscriber(ctx, sub); err != nil {
+		return err
+	}
+	return s.flush()
+}
+
+func (s *fileStore) DeleteSubscriber(ctx context.Context, chatID string) error {
+	if err := s.memoryStore.DeleteSubscriber(ctx, chatID); err != nil {
+		return err
+	}
+	return s.flush()
+}
+
+func (s *fileStore) SaveAlert(ctx context.Context, rule *AlertRule) error {
+	if err := s.memoryStore.SaveAlert(ctx, rule); err != nil {
+		return err
+	}
+	return s.flush()
+}
+
+func (s *fileStore) DeleteAlert(ctx context.Context, id string) error {
+	if err := s.memoryStore.DeleteAlert(ctx, id); err != nil {
+		return err
+	}
+	return s.flush()
+}
+
+func (s *fileStore) Close() error {
+	return s.flush()
+}
+
+// flush writes the state to a temporary file and renames it into place so a
+// crash never leaves a truncated file behind.
+func (s *fileStore) flush() error {
+	s.flushMu.Lock()
+	defer s.flushMu.Unlock()
+
+	s.mu.RLock()
+	data, err := json.Marshal(&s.state)
+	s.mu.RUnlock()
+	if err != nil {
+		return err
+	}
+
+	if err := os.MkdirAll(filepath.Dir(s.path), 0o755); err != nil {
+		return err
+	}
+	tmp := s.path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0o644); err != nil {
+		return err
+	}
+	return os.Rename(tmp, s.path)
+}
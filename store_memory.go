@@ -0,0 +1,166 @@
+package main
+
+import (
+	"context"
+	"slices"
+	"sort"
+	"sync"
+	"time"
+)
+
+// memoryState is the full content of a memoryStore. It is exported field by
+// field so the file backend can serialize it as-is.
+type memoryState struct {
+	Prices      map[string]*GoldPrice            `json:"prices"`
+	History     map[string]map[string]PricePoint `json:"history"`
+	Subscribers map[string]*Subscriber           `json:"subscribers"`
+	Alerts      map[string]*AlertRule            `json:"alerts"`
+}
+
+// memoryStore keeps everything in process memory. It is meant for tests and
+// for running the service without any external dependency.
+type memoryStore struct {
+	mu    sync.RWMutex
+	state memoryState
+}
+
+func newMemoryStore() *memoryStore {
+	return &memoryStore{
+		state: memoryState{
+			Prices:      make(map[string]*GoldPrice),
+			History:     make(map[string]map[string]PricePoint),
+			Subscribers: make(map[string]*Subscriber),
+			Alerts:      make(map[string]*AlertRule),
+		},
+	}
+}
+
+func (s *memoryStore) SaveGoldPrice(ctx context.Context, goldType string, goldPrice *GoldPrice) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	cp := *goldPrice
+	s.state.Prices[goldType] = &cp
+	return nil
+}
+
+func (s *memoryStore) GetGoldPrice(ctx context.Context, goldType string) (*GoldPrice, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	goldPrice, ok := s.state.Prices[goldType]
+	if !ok {
+		return nil, errNotFound
+	}
+	cp := *goldPrice
+	return &cp, nil
+}
+
+func (s *memoryStore) MergeHistory(ctx context.Context, goldType string, points []PricePoint) (added, updated int, err error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	series, ok := s.state.History[goldType]
+	if !ok {
+		series = make(map[string]PricePoint)
+		s.state.History[goldType] = series
+	}
+	for _, p := range points {
+		old, exists := series[p.Date]
+		switch {
+		case !exists:
+			added++
+		case !old.samePrice(p):
+			updated++
+		default:
+			continue
+		}
+		series[p.Date] = p
+	}
+	return added, updated, nil
+}
+
+func (s *memoryStore) History(ctx context.Context, goldType string, from, to time.Time) ([]PricePoint, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	points := make([]PricePoint, 0, len(s.state.History[goldType]))
+	for date, p := range s.state.History[goldType] {
+		if inHistoryRange(date, from, to) {
+			points = append(points, p)
+		}
+	}
+
+	sort.Slice(points, func(i, j int) bool { return points[i].Date < points[j].Date })
+	return points, nil
+}
+
+func (s *memoryStore) SaveSubscriber(ctx context.Context, sub *Subscriber) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.state.Subscribers[sub.ChatID] = cloneSubscriber(sub)
+	return nil
+}
+
+func (s *memoryStore) DeleteSubscriber(ctx context.Context, chatID string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	delete(s.state.Subscribers, chatID)
+	return nil
+}
+
+func (s *memoryStore) ListSubscribers(ctx context.Context) ([]*Subscriber, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	subscribers := make([]*Subscriber, 0, len(s.state.Subscribers))
+	for _, sub := range s.state.Subscribers {
+		subscribers = append(subscribers, cloneSubscriber(sub))
+	}
+
+	sort.Slice(subscribers, func(i, j int) bool { return subscribers[i].CreatedAt.Before(subscribers[j].CreatedAt) })
+	return subscribers, nil
+}
+
+func cloneSubscriber(sub *Subscriber) *Subscriber {
+	cp := *sub
+	cp.Providers = slices.Clone(sub.Providers)
+	return &cp
+}
+
+func (s *memoryStore) SaveAlert(ctx context.Context, rule *AlertRule) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	cp := *rule
+	s.state.Alerts[rule.ID] = &cp
+	return nil
+}
+
+func (s *memoryStore) DeleteAlert(ctx context.Context, id string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	delete(s.state.Alerts, id)
+	return nil
+}
+
+func (s *memoryStore) ListAlerts(ctx context.Context) ([]*AlertRule, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	rules := make([]*AlertRule, 0, len(s.state.Alerts))
+	for _, rule := range s.state.Alerts {
+		cp := *rule
+		rules = append(rules, &cp)
+	}
+
+	sort.Slice(rules, func(i, j int) bool { return rules[i].CreatedAt.Before(rules[j].CreatedAt) })
+	return rules, nil
+}
+
+func (s *memoryStore) Close() error {
+	return nil
+}
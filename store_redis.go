@@ -0,0 +1,206 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+)
+
+const (
+	redisKeyPrefix      = "gold_price:"
+	redisHistoryPrefix  = "gold_history:"
+	redisSubscribersKey = "telegram_subscribers"
+	redisAlertsKey      = "telegram_alerts"
+)
+
+// redisStore keeps snapshots as JSON strings, history as one hash per gold
+// type keyed by date, and subscribers and alert rules in one hash each keyed
+// by chat ID and rule ID.
+type redisStore struct {
+	rdb *redis.Client
+}
+
+func newRedisStore(addr, password string, db int) (*redisStore, error) {
+	rdb := redis.NewClient(&redis.Options{
+		Addr:     addr,
+		Password: password,
+		DB:       db,
+	})
+
+	// Test Redis connection
+	if _, err := rdb.Ping(ctx).Result(); err != nil {
+		rdb.Close()
+		return nil, fmt.Errorf("failed to connect to Redis at %s: %w", addr, err)
+	}
+	return &redisStore{rdb: rdb}, nil
+}
+
+func (s *redisStore) SaveGoldPrice(ctx context.Context, goldType string, goldPrice *GoldPrice) error {
+	key := redisKeyPrefix + goldType
+
+	jsonData, err := json.Marshal(goldPrice)
+	if err != nil {
+		return err
+	}
+
+	return s.rdb.Set(ctx, key, jsonData, 0).Err()
+}
+
+func (s *redisStore) GetGoldPrice(ctx context.Context, goldType string) (*GoldPrice, error) {
+	key := redisKeyPrefix + goldType
+
+	val, err := s.rdb.Get(ctx, key).Result()
+	if errors.Is(err, redis.Nil) {
+		return nil, errNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var goldPrice GoldPrice
+	if err := json.Unmarshal([]byte(val), &goldPrice); err != nil {
+		return nil, err
+	}
+
+	return &goldPrice, nil
+}
+
+func (s *redisStore) MergeHistory(ctx context.Context, goldType string, points []PricePoint) (added, updated int, err error) {
+	if len(points) == 0 {
+		return 0, 0, nil
+	}
+	key := redisHistoryPrefix + goldType
+
+	fields := make([]string, len(points))
+	for i, p := range points {
+		fields[i] = p.Date
+	}
+	existing, err := s.rdb.HMGet(ctx, key, fields...).Result()
+	if err != nil {
+		return 0, 0, err
+	}
+
+	values := make(map[string]interface{})
+	for i, p := range points {
+		jsonData, err := json.Marshal(p)
+		if err != nil {
+			return 0, 0, err
+		}
+		var old PricePoint
+		if raw, ok := existing[i].(string); ok {
+			json.Unmarshal([]byte(raw), &old)
+		}
+		switch {
+		case existing[i] == nil:
+			added++
+		case !old.samePrice(p):
+			updated++
+		default:
+			continue
+		}
+		values[p.Date] = jsonData
+	}
+
+	if len(values) == 0 {
+		return 0, 0, nil
+	}
+	if err := s.rdb.HSet(ctx, key, values).Err(); err != nil {
+		return 0, 0, err
+	}
+	return added, updated, nil
+}
+
+func (s *redisStore) History(ctx context.Context, goldType string, from, to time.Time) ([]PricePoint, error) {
+	key := redisHistoryPrefix + goldType
+
+	vals, err := s.rdb.HGetAll(ctx, key).Result()
+	if err != nil {
+		return nil, err
+	}
+
+	points := make([]PricePoint, 0, len(vals))
+	for date, val := range vals {
+		if !inHistoryRange(date, from, to) {
+			continue
+		}
+		var p PricePoint
+		if err := json.Unmarshal([]byte(val), &p); err != nil {
+			return nil, fmt.Errorf("corrupt history point %s: %w", date, err)
+		}
+		points = append(points, p)
+	}
+
+	sort.Slice(points, func(i, j int) bool { return points[i].Date < points[j].Date })
+	return points, nil
+}
+
+func (s *redisStore) SaveSubscriber(ctx context.Context, sub *Subscriber) error {
+	jsonData, err := json.Marshal(sub)
+	if err != nil {
+		return err
+	}
+	return s.rdb.HSet(ctx, redisSubscribersKey, sub.ChatID, jsonData).Err()
+}
+
+func (s *redisStore) DeleteSubscriber(ctx context.Context, chatID string) error {
+	return s.rdb.HDel(ctx, redisSubscribersKey, chatID).Err()
+}
+
+func (s *redisStore) ListSubscribers(ctx context.Context) ([]*Subscriber, error) {
+	vals, err := s.rdb.HGetAll(ctx, redisSubscribersKey).Result()
+	if err != nil {
+		return nil, err
+	}
+
+	subscribers := make([]*Subscriber, 0, len(vals))
+	for chatID, val := range vals {
+		var sub Subscriber
+		if err := json.Unmarshal([]byte(val), &sub); err != nil {
+			return nil, fmt.Errorf("corrupt subscriber %s: %w", chatID, err)
+		}
+		subscribers = append(subscribers, &sub)
+	}
+
+	sort.Slice(subscribers, func(i, j int) bool { return subscribers[i].CreatedAt.Before(subscribers[j].CreatedAt) })
+	return subscribers, nil
+}
+
+func (s *redisStore) SaveAlert(ctx context.Context, rule *AlertRule) error {
+	jsonData, err := json.Marshal(rule)
+	if err != nil {
+		return err
+	}
+	return s.rdb.HSet(ctx, redisAlertsKey, rule.ID, jsonData).Err()
+}
+
+func (s *redisStore) DeleteAlert(ctx context.Context, id string) error {
+	return s.rdb.HDel(ctx, redisAlertsKey, id).Err()
+}
+
+func (s *redisStore) ListAlerts(ctx context.Context) ([]*AlertRule, error) {
+	vals, err := s.rdb.HGetAll(ctx, redisAlertsKey).Result()
+	if err != nil {
+		return nil, err
+	}
+
+	rules := make([]*AlertRule, 0, len(vals))
+	for id, val := range vals {
+		var rule AlertRule
+		if err := json.Unmarshal([]byte(val), &rule); err != nil {
+			return nil, fmt.Errorf("corrupt alert rule %s: %w", id, err)
+		}
+		rules = append(rules, &rule)
+	}
+
+	sort.Slice(rules, func(i, j int) bool { return rules[i].CreatedAt.Before(rules[j].CreatedAt) })
+	return rules, nil
+}
+
+func (s *redisStore) Close() error {
+	return s.rdb.Close()
+}
@@ -0,0 +1,201 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+	"time"
+)
+
+// storeBackends opens every backend available to the test. Redis is only
+// tested when REDIS_TEST_ADDR names a server whose DB 15 may be written.
+func storeBackends(t *testing.T) map[string]func(t *testing.T) Store {
+	backends := map[string]func(t *testing.T) Store{
+		"memory": func(t *testing.T) Store { return newMemoryStore() },
+		"file": func(t *testing.T) Store {
+			s, err := newFileStore(filepath.Join(t.TempDir(), "data", "gold.json"))
+			if err != nil {
+				t.Fatal(err)
+			}
+			return s
+		},
+	}
+	if addr := os.Getenv("REDIS_TEST_ADDR"); addr != "" {
+		backends["redis"] = func(t *testing.T) Store {
+			s, err := newRedisStore(addr, "", 15)
+			if err != nil {
+				t.Fatal(err)
+			}
+			t.Cleanup(func() {
+				s.rdb.Del(context.Background(), redisKeyPrefix+"sjc", redisHistoryPrefix+"sjc")
+				s.Close()
+			})
+			return s
+		}
+	}
+	return backends
+}
+
+func TestStoreRoundTrip(t *testing.T) {
+	ctx := context.Background()
+	for name, open := range storeBackends(t) {
+		t.Run(name, func(t *testing.T) {
+			s := open(t)
+
+			if _, err := s.GetGoldPrice(ctx, "sjc"); !errors.Is(err, errNotFound) {
+				t.Fatalf("GetGoldPrice on an empty store: %v, want errNotFound", err)
+			}
+
+			want := &GoldPrice{
+				Type:       "sjc",
+				Dates:      []string{"15/03", "16/03"},
+				BuyPrices:  []float64{118.5e6, 119e6},
+				SellPrices: []float64{120.5e6, 121e6},
+				UpdatedAt:  time.Date(2025, 3, 16, 9, 0, 0, 0, time.UTC),
+				Source:     "24h",
+			}
+			if err := s.SaveGoldPrice(ctx, "sjc", want); err != nil {
+				t.Fatal(err)
+			}
+			got, err := s.GetGoldPrice(ctx, "sjc")
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got.Type != want.Type || got.Source != want.Source || !got.UpdatedAt.Equal(want.UpdatedAt) ||
+				!slices.Equal(got.Dates, want.Dates) || !slices.Equal(got.BuyPrices, want.BuyPrices) || !slices.Equal(got.SellPrices, want.SellPrices) {
+				t.Errorf("GetGoldPrice = %+v, want %+v", got, want)
+			}
+		})
+	}
+}
+
+func TestStoreHistory(t *testing.T) {
+	ctx := context.Background()
+	day := func(d int) time.Time { return time.Date(2025, 3, d, 0, 0, 0, 0, time.UTC) }
+
+	for name, open := range storeBackends(t) {
+		t.Run(name, func(t *testing.T) {
+			s := open(t)
+
+			first := []PricePoint{
+				{Date: "2025-03-14", Buy: 118e6, Sell: 120e6, Source: "24h"},
+				{Date: "2025-03-15", Buy: 118.5e6, Sell: 120.5e6, Source: "24h"},
+			}
+			if added, updated, err := s.MergeHistory(ctx, "sjc", first); err != nil || added != 2 || updated != 0 {
+				t.Fatalf("first merge: added %d, updated %d, err %v; want 2, 0", added, updated, err)
+			}
+
+			// The next chart window, from a fallback source, repeats one day,
+			// corrects another and adds a third. The repeated day keeps the
+			// source that first reported it.
+			second := []PricePoint{
+				{Date: "2025-03-14", Buy: 118e6, Sell: 120e6, Source: "backup"},
+				{Date: "2025-03-15", Buy: 118.6e6, Sell: 120.6e6, Source: "backup"},
+				{Date: "2025-03-16", Buy: 119e6, Sell: 121e6, Source: "backup"},
+			}
+			if added, updated, err := s.MergeHistory(ctx, "sjc", second); err != nil || added != 1 || updated != 1 {
+				t.Fatalf("second merge: added %d, updated %d, err %v; want 1, 1", added, updated, err)
+			}
+			if added, updated, err := s.MergeHistory(ctx, "sjc", nil); err != nil || added+updated != 0 {
+				t.Fatalf("empty merge: added %d, updated %d, err %v", added, updated, err)
+			}
+
+			tests := []struct {
+				name     string
+				from, to time.Time
+				want     []string
+			}{
+				{"everything", time.Time{}, time.Time{}, []string{"2025-03-14", "2025-03-15", "2025-03-16"}},
+				{"from", day(15), time.Time{}, []string{"2025-03-15", "2025-03-16"}},
+				{"to", time.Time{}, day(15), []string{"2025-03-14", "2025-03-15"}},
+				{"one day", day(16), day(16), []string{"2025-03-16"}},
+				{"before the history", day(1), day(13), nil},
+			}
+			for _, tt := range tests {
+				points, err := s.History(ctx, "sjc", tt.from, tt.to)
+				if err != nil {
+					t.Fatal(err)
+				}
+				var dates []string
+				for _, p := range points {
+					dates = append(dates, p.Date)
+				}
+				if !slices.Equal(dates, tt.want) {
+					t.Errorf("%s: got %v, want %v", tt.name, dates, tt.want)
+				}
+			}
+
+			points, err := s.History(ctx, "sjc", day(14), day(15))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if want := []PricePoint{first[0], second[1]}; !slices.Equal(points, want) {
+				t.Errorf("repeated and corrected points = %+v, want %+v", points, want)
+			}
+			if points, err := s.History(ctx, "doji_hn", time.Time{}, time.Time{}); err != nil || len(points) != 0 {
+				t.Errorf("History of another type = %v, %v; want nothing", points, err)
+			}
+		})
+	}
+}
+
+func TestFileStoreReopen(t *testing.T) {
+	ctx := context.Background()
+	path := filepath.Join(t.TempDir(), "gold.json")
+
+	s, err := newFileStore(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := s.SaveGoldPrice(ctx, "sjc", &GoldPrice{Type: "sjc", Dates: []string{"16/03"}, BuyPrices: []float64{119e6}, SellPrices: []float64{121e6}}); err != nil {
+		t.Fatal(err)
+	}
+	point := PricePoint{Date: "2025-03-16", Buy: 119e6, Sell: 121e6}
+	if _, _, err := s.MergeHistory(ctx, "sjc", []PricePoint{point}); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	reopened, err := newFileStore(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, err := reopened.GetGoldPrice(ctx, "sjc"); err != nil || got.BuyPrices[0] != 119e6 {
+		t.Errorf("snapshot after reopening = %+v, %v", got, err)
+	}
+	if points, err := reopened.History(ctx, "sjc", time.Time{}, time.Time{}); err != nil || len(points) != 1 || points[0] != point {
+		t.Errorf("history after reopening = %+v, %v", points, err)
+	}
+	if _, err := os.Stat(path + ".tmp"); !errors.Is(err, os.ErrNotExist) {
+		t.Errorf("temporary file left behind: %v", err)
+	}
+
+	if err := os.WriteFile(path, []byte("{not json"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := newFileStore(path); err == nil {
+		t.Error("newFileStore accepted a corrupt file")
+	}
+}
+
+func TestMemoryStoreCopies(t *testing.T) {
+	ctx := context.Background()
+	s := newMemoryStore()
+
+	saved := &GoldPrice{Type: "sjc", Source: "24h"}
+	if err := s.SaveGoldPrice(ctx, "sjc", saved); err != nil {
+		t.Fatal(err)
+	}
+	saved.Source = "changed after saving"
+	got, _ := s.GetGoldPrice(ctx, "sjc")
+	got.Type = "changed after loading"
+
+	if again, _ := s.GetGoldPrice(ctx, "sjc"); again.Source != "24h" || again.Type != "sjc" {
+		t.Errorf("store shares its snapshot with callers: %+v", again)
+	}
+}
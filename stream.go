@@ -0,0 +1,210 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"slices"
+	"strconv"
+	"sync"
+	"time"
+)
+
+const (
+	streamBacklog   = 256              // events kept for Last-Event-ID resume
+	streamBuffer    = 32               // events queued per client before it is dropped
+	streamHeartbeat = 15 * time.Second // comment line keeping proxies from closing idle streams
+	streamRetry     = 5 * time.Second  // reconnect delay suggested to clients
+)
+
+// streamHub serves GET /api/stream, a Server-Sent Events feed of
+// price.updated events. It is a Notifier so it sees the same change-only
+// events as the webhooks. The latest events are kept so that a client
+// reconnecting with Last-Event-ID receives the ones it missed.
+type streamHub struct {
+	mu      sync.Mutex
+	nextID  uint64
+	backlog []streamEvent // oldest first
+	clients map[*streamClient]struct{}
+	closed  bool
+}
+
+type streamEvent struct {
+	ID       uint64
+	Type     string
+	GoldType string
+	Data     []byte
+}
+
+type streamClient struct {
+	types  []string // empty means every gold type
+	events chan streamEvent
+}
+
+func (c *streamClient) wants(ev streamEvent) bool {
+	return len(c.types) == 0 || slices.Contains(c.types, ev.GoldType)
+}
+
+func newStreamHub() *streamHub {
+	return &streamHub{
+		// IDs keep increasing across restarts, so an ID from a previous run
+		// resumes from the start of the backlog instead of skipping it.
+		nextID:  uint64(time.Now().UnixMilli()),
+		clients: make(map[*streamClient]struct{}),
+	}
+}
+
+func (h *streamHub) Name() string {
+	return "stream"
+}
+
+func (h *streamHub) Notify(ctx context.Context, ev Event) error {
+	if ev.Type != eventPriceUpdated || ev.Price == nil {
+		return nil
+	}
+	data, err := json.Marshal(ev)
+	if err != nil {
+		return err
+	}
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	h.nextID++
+	sev := streamEvent{ID: h.nextID, Type: ev.Type, GoldType: ev.Price.GoldType, Data: data}
+	h.backlog = append(h.backlog, sev)
+	if len(h.backlog) > streamBacklog {
+		h.backlog = slices.Clone(h.backlog[len(h.backlog)-streamBacklog:])
+	}
+
+	for c := range h.clients {
+		if !c.wants(sev) {
+			continue
+		}
+		select {
+		case c.events <- sev:
+		default:
+			// Too slow; it reconnects with Last-Event-ID and catches up
+			// from the backlog.
+			delete(h.clients, c)
+			close(c.events)
+		}
+	}
+	return nil
+}
+
+// subscribe registers a client and returns the backlog events after lastID
+// it should be sent first. It returns nil once the hub is closed.
+func (h *streamHub) subscribe(types []string, lastID uint64, resume bool) (*streamClient, []streamEvent) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if h.closed {
+		return nil, nil
+	}
+	c := &streamClient{types: types, events: make(chan streamEvent, streamBuffer)}
+	h.clients[c] = struct{}{}
+
+	var replay []streamEvent
+	if resume {
+		for _, ev := range h.backlog {
+			if ev.ID > lastID && c.wants(ev) {
+				replay = append(replay, ev)
+			}
+		}
+	}
+	return c, replay
+}
+
+func (h *streamHub) unsubscribe(c *streamClient) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if _, ok := h.clients[c]; ok {
+		delete(h.clients, c)
+		close(c.events)
+	}
+}
+
+// Close ends every open stream so the HTTP server can shut down.
+func (h *streamHub) Close() {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	h.closed = true
+	for c := range h.clients {
+		delete(h.clients, c)
+		close(c.events)
+	}
+}
+
+// ServeHTTP serves GET /api/stream?types=sjc,doji_hn. The last event ID is
+// read from the Last-Event-ID header browsers send when reconnecting, or
+// from the lastEventId parameter.
+func (h *streamHub) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	var goldTypes []string
+	if v := r.URL.Query().Get("types"); v != "" {
+		goldTypes = splitList(v)
+	}
+	for _, goldType := range goldTypes {
+		if _, ok := enabledProvider(goldType); !ok {
+			respondWithError(w, http.StatusBadRequest, fmt.Sprintf("Unknown gold type %q", goldType))
+			return
+		}
+	}
+
+	lastEventID := r.Header.Get("Last-Event-ID")
+	if lastEventID == "" {
+		lastEventID = r.URL.Query().Get("lastEventId")
+	}
+	lastID, err := strconv.ParseUint(lastEventID, 10, 64)
+	resume := err == nil
+
+	client, replay := h.subscribe(goldTypes, lastID, resume)
+	if client == nil {
+		respondWithError(w, http.StatusServiceUnavailable, "Server is shutting down")
+		return
+	}
+	defer h.unsubscribe(client)
+
+	rc := http.NewResponseController(w)
+	rc.SetWriteDeadline(time.Time{})
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+	w.Header().Set("X-Accel-Buffering", "no") // nginx
+	w.WriteHeader(http.StatusOK)
+
+	fmt.Fprintf(w, "retry: %d\n\n", streamRetry.Milliseconds())
+	for _, ev := range replay {
+		writeStreamEvent(w, ev)
+	}
+	if err := rc.Flush(); err != nil {
+		return
+	}
+
+	heartbeat := time.NewTicker(streamHeartbeat)
+	defer heartbeat.Stop()
+	for {
+		select {
+		case <-r.Context().Done():
+			return
+		case ev, ok := <-client.events:
+			if !ok {
+				return
+			}
+			writeStreamEvent(w, ev)
+		case <-heartbeat.C:
+			fmt.Fprint(w, ": ping\n\n")
+		}
+		if err := rc.Flush(); err != nil {
+			return
+		}
+	}
+}
+
+func writeStreamEvent(w http.ResponseWriter, ev streamEvent) {
+	fmt.Fprintf(w, "id: %d\nevent: %s\ndata: %s\n\n", ev.ID, ev.Type, ev.Data)
+}
@@ -0,0 +1,175 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+func priceEvent(goldType string, sell float64) Event {
+	ev := newEvent(eventPriceUpdated)
+	ev.Price = &PriceEvent{GoldType: goldType, Sell: sell}
+	return ev
+}
+
+// openStream connects to the stream at url and returns once the hub has
+// registered the client, which is when the response headers arrive.
+func openStream(t *testing.T, url, lastEventID string) *bufio.Reader {
+	t.Helper()
+	ctx, cancel := context.WithCancel(context.Background())
+	t.Cleanup(cancel)
+	req, _ := http.NewRequestWithContext(ctx, "GET", url, nil)
+	if lastEventID != "" {
+		req.Header.Set("Last-Event-ID", lastEventID)
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { resp.Body.Close() })
+	if resp.StatusCode != http.StatusOK || resp.Header.Get("Content-Type") != "text/event-stream" {
+		t.Fatalf("status %d, Content-Type %q", resp.StatusCode, resp.Header.Get("Content-Type"))
+	}
+	return bufio.NewReader(resp.Body)
+}
+
+// nextStreamEvent reads the next event, skipping the retry hint and
+// heartbeats.
+func nextStreamEvent(t *testing.T, r *bufio.Reader) (id uint64, ev Event) {
+	t.Helper()
+	fields := map[string]string{}
+	for {
+		line, err := r.ReadString('\n')
+		if err != nil {
+			t.Fatalf("stream ended: %v", err)
+		}
+		line = strings.TrimSuffix(line, "\n")
+		if line != "" {
+			if name, value, ok := strings.Cut(line, ": "); ok {
+				fields[name] = value
+			}
+			continue
+		}
+		if fields["data"] == "" {
+			fields = map[string]string{}
+			continue
+		}
+		if fields["event"] != eventPriceUpdated {
+			t.Errorf("event type %q", fields["event"])
+		}
+		id, err := strconv.ParseUint(fields["id"], 10, 64)
+		if err != nil {
+			t.Fatalf("event id %q: %v", fields["id"], err)
+		}
+		if err := json.Unmarshal([]byte(fields["data"]), &ev); err != nil {
+			t.Fatal(err)
+		}
+		return id, ev
+	}
+}
+
+func TestStreamFanOut(t *testing.T) {
+	hub := newStreamHub()
+	srv := httptest.NewServer(hub)
+	defer srv.Close()
+	defer hub.Close()
+
+	all := openStream(t, srv.URL, "")
+	sjcOnly := openStream(t, srv.URL+"?types=sjc", "")
+
+	for _, ev := range []Event{priceEvent("doji_hn", 119e6), priceEvent("sjc", 121e6), newEvent(eventDigestDaily)} {
+		if err := hub.Notify(context.Background(), ev); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	firstID, first := nextStreamEvent(t, all)
+	secondID, second := nextStreamEvent(t, all)
+	if first.Price.GoldType != "doji_hn" || second.Price.GoldType != "sjc" || secondID != firstID+1 {
+		t.Errorf("unfiltered stream got %s (%d), %s (%d)", first.Price.GoldType, firstID, second.Price.GoldType, secondID)
+	}
+	if id, ev := nextStreamEvent(t, sjcOnly); ev.Price.GoldType != "sjc" || id != secondID {
+		t.Errorf("filtered stream got %s (%d), want sjc (%d)", ev.Price.GoldType, id, secondID)
+	}
+}
+
+func TestStreamResume(t *testing.T) {
+	hub := newStreamHub()
+	srv := httptest.NewServer(hub)
+	defer srv.Close()
+	defer hub.Close()
+
+	for _, ev := range []Event{priceEvent("sjc", 120e6), priceEvent("doji_hn", 119e6), priceEvent("sjc", 121e6)} {
+		hub.Notify(context.Background(), ev)
+	}
+	firstID := hub.backlog[0].ID
+
+	tests := []struct {
+		name      string
+		query     string
+		lastID    string
+		wantSells []float64
+	}{
+		{"after the first event", "", strconv.FormatUint(firstID, 10), []float64{119e6, 121e6}},
+		{"filtered", "?types=sjc", strconv.FormatUint(firstID, 10), []float64{121e6}},
+		{"from an earlier run", "", "1", []float64{120e6, 119e6, 121e6}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := openStream(t, srv.URL+tt.query, tt.lastID)
+			for _, want := range tt.wantSells {
+				if _, ev := nextStreamEvent(t, r); ev.Price.Sell != want {
+					t.Errorf("replayed sell %g, want %g", ev.Price.Sell, want)
+				}
+			}
+		})
+	}
+
+	// Without Last-Event-ID nothing is replayed: the next event is live.
+	r := openStream(t, srv.URL, "")
+	hub.Notify(context.Background(), priceEvent("pnj_hn", 117e6))
+	if _, ev := nextStreamEvent(t, r); ev.Price.GoldType != "pnj_hn" {
+		t.Errorf("new client got %s first, want the live pnj_hn event", ev.Price.GoldType)
+	}
+}
+
+func TestStreamRejectsUnknownTypes(t *testing.T) {
+	hub := newStreamHub()
+	rec := httptest.NewRecorder()
+	hub.ServeHTTP(rec, httptest.NewRequest("GET", "/api/stream?types=sjc,vang_gia", nil))
+	if rec.Code != http.StatusBadRequest {
+		t.Errorf("status %d, want 400", rec.Code)
+	}
+}
+
+func TestStreamClose(t *testing.T) {
+	hub := newStreamHub()
+	srv := httptest.NewServer(hub)
+	defer srv.Close()
+
+	r := openStream(t, srv.URL, "")
+	hub.Close()
+
+	done := make(chan error, 1)
+	go func() {
+		_, err := r.ReadString(0)
+		done <- err
+	}()
+	select {
+	case <-done:
+	case <-time.After(2 * time.Second):
+		t.Fatal("Close did not end the open stream")
+	}
+
+	rec := httptest.NewRecorder()
+	hub.ServeHTTP(rec, httptest.NewRequest("GET", "/api/stream", nil))
+	if rec.Code != http.StatusServiceUnavailable {
+		t.Errorf("status after Close %d, want 503", rec.Code)
+	}
+}
@@ -0,0 +1,286 @@
+package main
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"log"
+	"slices"
+	"sync"
+	"time"
+
+	bottelegram "pricegoldtoday/bot"
+
+	"github.com/robfig/cron/v3"
+)
+
+// Notification modes of a subscriber.
+const (
+	notifyChanges = "changes" // only when prices changed, plus the daily digest
+	notifyAlways  = "always"  // on every schedule tick
+)
+
+// Subscriber is a Telegram chat receiving the price digest.
+type Subscriber struct {
+	ChatID         string    `json:"chat_id"`
+	Providers      []string  `json:"providers,omitempty"` // empty means every gold type
+	Language       string    `json:"language"`
+	Unit           string    `json:"unit,omitempty"` // bottelegram.UnitLuong or UnitChi
+	Schedule       string    `json:"schedule"`       // cron spec
+	CreatedAt      time.Time `json:"created_at"`
+	LastNotifiedAt time.Time `json:"last_notified_at"`
+
+	// Notify is notifyChanges or notifyAlways; empty means notifyChanges.
+	Notify string `json:"notify,omitempty"`
+	// DailyDigest is a cron spec at which the table is sent even if it did
+	// not change; empty disables it.
+	DailyDigest string `json:"daily_digest,omitempty"`
+	// LastFingerprint identifies the prices in the last digest sent.
+	LastFingerprint string    `json:"last_fingerprint,omitempty"`
+	LastDigestAt    time.Time `json:"last_digest_at"`
+}
+
+// Wants reports whether the subscriber selected goldType.
+func (s *Subscriber) Wants(goldType string) bool {
+	return len(s.Providers) == 0 || slices.Contains(s.Providers, goldType)
+}
+
+// Due reports whether the subscriber's schedule has fired since the last
+// notification.
+func (s *Subscriber) Due(now time.Time) (bool, error) {
+	sched, err := cron.ParseStandard(s.Schedule)
+	if err != nil {
+		return false, err
+	}
+	return !sched.Next(s.LastNotifiedAt).After(now), nil
+}
+
+// DigestOptions are the subscriber's rendering preferences.
+func (s *Subscriber) DigestOptions() bottelegram.DigestOptions {
+	return bottelegram.DigestOptions{Locale: s.Language, Unit: s.Unit}
+}
+
+// ShouldSend decides whether a due subscriber gets the digest whose prices
+// have the given fingerprint. daily is true when the daily digest is due.
+func (s *Subscriber) ShouldSend(fingerprint string, now time.Time) (send, daily bool, err error) {
+	if s.DailyDigest != "" {
+		sched, err := cron.ParseStandard(s.DailyDigest)
+		if err != nil {
+			return false, false, err
+		}
+		daily = !sched.Next(s.LastDigestAt).After(now)
+	}
+	if s.Notify == notifyAlways || daily {
+		return true, daily, nil
+	}
+	return fingerprint != s.LastFingerprint, false, nil
+}
+
+// sameDigest reports whether o renders the same digest as s: the same gold
+// types, language and unit.
+func (s *Subscriber) sameDigest(o *Subscriber) bool {
+	return slices.Equal(s.Providers, o.Providers) && s.Language == o.Language && s.Unit == o.Unit
+}
+
+// subscribersMu serializes read-modify-write cycles on subscribers, so that
+// a digest run and a command such as /lang or /unsubscribe landing at the
+// same time do not overwrite each other.
+var subscribersMu sync.Mutex
+
+// modifySubscriber re-reads the chat and saves it after update. found is
+// false when the chat is not subscribed, in which case nothing is saved.
+func modifySubscriber(ctx context.Context, chatID string, update func(*Subscriber)) (found bool, err error) {
+	subscribersMu.Lock()
+	defer subscribersMu.Unlock()
+
+	sub, err := findSubscriber(ctx, chatID)
+	if err != nil || sub == nil {
+		return false, err
+	}
+	update(sub)
+	return true, store.SaveSubscriber(ctx, sub)
+}
+
+// removeSubscriber unsubscribes the chat.
+func removeSubscriber(ctx context.Context, chatID string) error {
+	subscribersMu.Lock()
+	defer subscribersMu.Unlock()
+	return store.DeleteSubscriber(ctx, chatID)
+}
+
+func newSubscriber(chatID string, cfg TelegramConfig) *Subscriber {
+	now := time.Now()
+	return &Subscriber{
+		ChatID:         chatID,
+		Language:       cfg.Language,
+		Unit:           cfg.Unit,
+		Schedule:       cfg.Schedule,
+		CreatedAt:      now,
+		LastNotifiedAt: now,
+		Notify:         cfg.Notify,
+		DailyDigest:    cfg.DailyDigest,
+		LastDigestAt:   now,
+	}
+}
+
+// seedDefaultSubscriber registers the chat from the bot config so existing
+// single-chat deployments keep receiving the digest.
+func seedDefaultSubscriber(bot *bottelegram.Bot, cfg TelegramConfig) {
+	chatID := bot.DefaultChatID()
+	if chatID == "" {
+		return
+	}
+	existing, err := findSubscriber(ctx, chatID)
+	if err != nil {
+		log.Printf("Cannot list Telegram subscribers: %v", err)
+		return
+	}
+	if existing != nil {
+		return
+	}
+
+	sub := newSubscriber(chatID, cfg)
+	sub.LastNotifiedAt = time.Time{} // send the first digest right away
+	if err := store.SaveSubscriber(ctx, sub); err != nil {
+		log.Printf("Cannot register default Telegram chat: %v", err)
+		return
+	}
+	log.Printf("Registered default Telegram chat %s", chatID)
+}
+
+// notifySubscribers sends the digest to every subscriber that is due and
+// whose prices changed since the last digest, or whose daily digest is due.
+// A failing chat does not affect the others, and chats that blocked the bot
+// are removed. Only the notification fields are written back, to the
+// subscriber as stored after sending, so preferences changed meanwhile are
+// kept and chats that unsubscribed meanwhile stay removed.
+func notifySubscribers(ctx context.Context, bot *bottelegram.Bot, now time.Time) {
+	subscribers, err := store.ListSubscribers(ctx)
+	if err != nil {
+		log.Printf("Cannot list Telegram subscribers: %v", err)
+		return
+	}
+
+	var due []*Subscriber
+	for _, sub := range subscribers {
+		ok, err := sub.Due(now)
+		if err != nil {
+			log.Printf("Invalid schedule %q for chat %s: %v", sub.Schedule, sub.ChatID, err)
+			continue
+		}
+		if ok {
+			due = append(due, sub)
+		}
+	}
+	if len(due) == 0 {
+		return
+	}
+
+	goldPrices := loadGoldPrices(ctx, GOLDTYPES)
+	for _, sub := range due {
+		data := goldPriceResponseFor(sub, goldPrices)
+		if len(data.Providers) == 0 {
+			continue
+		}
+		fingerprint := digestFingerprint(data)
+		send, daily, err := sub.ShouldSend(fingerprint, now)
+		if err != nil {
+			log.Printf("Invalid daily digest %q for chat %s: %v", sub.DailyDigest, sub.ChatID, err)
+			continue
+		}
+
+		sent := false
+		if send {
+			err := bot.SendGoldPriceNotification(sub.ChatID, data, sub.DigestOptions())
+			switch {
+			case bottelegram.IsBlocked(err):
+				log.Printf("Chat %s blocked the bot, unsubscribing: %v", sub.ChatID, err)
+				if err := removeSubscriber(ctx, sub.ChatID); err != nil {
+					log.Printf("Cannot remove chat %s: %v", sub.ChatID, err)
+				}
+				continue
+			case err != nil:
+				log.Printf("Error sending Telegram notification to %s: %v", sub.ChatID, err)
+			default:
+				log.Printf("Sent gold price digest to chat %s (daily: %t)", sub.ChatID, daily)
+				sent = true
+				if daily {
+					sendDigestChart(ctx, bot, sub, data)
+				}
+			}
+		}
+
+		found, err := modifySubscriber(ctx, sub.ChatID, func(cur *Subscriber) {
+			// Record the attempt either way so a failing chat is retried at
+			// its next scheduled time rather than on every tick.
+			cur.LastNotifiedAt = now
+			if !sent {
+				return
+			}
+			if daily {
+				cur.LastDigestAt = now
+			}
+			// A chat that changed its preferences meanwhile keeps the reset
+			// fingerprint and gets the digest in its new format next time.
+			if cur.sameDigest(sub) {
+				cur.LastFingerprint = fingerprint
+			}
+		})
+		switch {
+		case err != nil:
+			log.Printf("Cannot update chat %s: %v", sub.ChatID, err)
+		case !found:
+			log.Printf("Chat %s unsubscribed during the digest run", sub.ChatID)
+		}
+	}
+}
+
+// sendDigestChart follows the daily digest with a chart of the same
+// providers. Failures only get logged; the table was already delivered.
+func sendDigestChart(ctx context.Context, bot *bottelegram.Bot, sub *Subscriber, data *bottelegram.GoldPriceResponse) {
+	goldTypes := make([]string, len(data.Providers))
+	for i, p := range data.Providers {
+		goldTypes[i] = p.Type
+	}
+	img, err := renderPriceChart(ctx, goldTypes, recentDays(defaultChartDays), chatChartOptions(sub.DigestOptions()))
+	if err != nil {
+		log.Printf("Cannot render digest chart for chat %s: %v", sub.ChatID, err)
+		return
+	}
+	caption := fmt.Sprintf(labelsFor(sub.Language).Chart, defaultChartDays)
+	if err := bot.SendPhoto(sub.ChatID, img, caption); err != nil {
+		log.Printf("Cannot send digest chart to chat %s: %v", sub.ChatID, err)
+	}
+}
+
+// digestFingerprint identifies the prices in a digest. Crawl times are left
+// out so a recrawl with the same prices is not a change.
+func digestFingerprint(data *bottelegram.GoldPriceResponse) string {
+	h := sha256.New()
+	for _, p := range data.Providers {
+		fmt.Fprintf(h, "%s|%v|%v|%v\n", p.Type, p.Dates, p.BuyPrices, p.SellPrices)
+	}
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// goldPriceResponseFor builds the bot payload with the subscriber's
+// providers, in catalog order.
+func goldPriceResponseFor(sub *Subscriber, goldPrices map[string]*GoldPrice) *bottelegram.GoldPriceResponse {
+	dataGold := &bottelegram.GoldPriceResponse{}
+	for _, provider := range providerCatalog {
+		goldPrice, ok := goldPrices[provider.ID]
+		if !ok || !sub.Wants(provider.ID) {
+			continue
+		}
+		dataGold.Providers = append(dataGold.Providers, bottelegram.GoldPriceData{
+			Type:       provider.ID,
+			Name:       provider.Name,
+			Dates:      goldPrice.Dates,
+			BuyPrices:  goldPrice.BuyPrices,
+			SellPrices: goldPrice.SellPrices,
+			UpdatedAt:  goldPrice.UpdatedAt.Format(time.RFC3339),
+		})
+	}
+	return dataGold
+}
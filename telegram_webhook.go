@@ -0,0 +1,49 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"os"
+	"time"
+)
+
+// runWebhookCommand registers or removes the Telegram webhook and exits:
+//
+//	pricegoldtoday webhook set [flags]
+//	pricegoldtoday webhook delete [flags]
+//
+// Flags and environment are the same as for the server.
+func runWebhookCommand(args []string) {
+	if len(args) == 0 || (args[0] != "set" && args[0] != "delete") {
+		fmt.Fprintln(os.Stderr, "usage: pricegoldtoday webhook set|delete [flags]")
+		os.Exit(2)
+	}
+	action := args[0]
+
+	cfg, err := loadConfig(args[1:])
+	if err != nil {
+		log.Fatalf("Failed to load configuration: %v", err)
+	}
+	cfg.Telegram.Required = true
+	bot := initTelegram(cfg.Telegram)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	switch action {
+	case "set":
+		if cfg.Telegram.Webhook.URL == "" {
+			log.Fatal("telegram.webhook.url is required to set the webhook")
+		}
+		if err := bot.SetWebhook(ctx, cfg.Telegram.Webhook.URL, cfg.Telegram.Webhook.Secret); err != nil {
+			log.Fatalf("setWebhook failed: %v", err)
+		}
+		log.Printf("Telegram webhook set to %s; use telegram.mode webhook", cfg.Telegram.Webhook.URL)
+	case "delete":
+		if err := bot.DeleteWebhook(ctx); err != nil {
+			log.Fatalf("deleteWebhook failed: %v", err)
+		}
+		log.Println("Telegram webhook deleted; polling can be used again")
+	}
+}
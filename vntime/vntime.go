@@ -0,0 +1,95 @@
+// Package vntime resolves the "dd/mm" dates used by Vietnamese price charts
+// into full calendar dates in the Asia/Ho_Chi_Minh time zone.
+package vntime
+
+import (
+	"fmt"
+	"time"
+	_ "time/tzdata" // the zone must resolve on hosts without tzdata
+)
+
+const (
+	// DateLayout is the ISO-8601 calendar date layout used for storage and APIs.
+	DateLayout = "2006-01-02"
+	// CategoryLayout is the day/month layout of chart categories.
+	CategoryLayout = "02/01"
+)
+
+// Location is the Asia/Ho_Chi_Minh zone that all prices are quoted in.
+var Location = loadLocation()
+
+func loadLocation() *time.Location {
+	loc, err := time.LoadLocation("Asia/Ho_Chi_Minh")
+	if err != nil {
+		return time.FixedZone("ICT", 7*60*60)
+	}
+	return loc
+}
+
+// Now returns the current time in Vietnam.
+func Now() time.Time {
+	return time.Now().In(Location)
+}
+
+// Day truncates t to midnight of its calendar day in Vietnam.
+func Day(t time.Time) time.Time {
+	t = t.In(Location)
+	return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, Location)
+}
+
+// Today returns midnight of the current day in Vietnam.
+func Today() time.Time {
+	return Day(time.Now())
+}
+
+// Category formats t as a "dd/mm" chart category in Vietnam.
+func Category(t time.Time) string {
+	return t.In(Location).Format(CategoryLayout)
+}
+
+// ResolveCategories turns chronologically ordered "dd/mm" categories into
+// dates. ref is when the chart was fetched: the last category is placed in
+// the latest year that does not put it after ref's day in Vietnam, and each
+// earlier category steps back a year whenever the sequence would otherwise
+// run backwards, so a series spanning New Year resolves correctly.
+func ResolveCategories(categories []string, ref time.Time) ([]time.Time, error) {
+	dates := make([]time.Time, len(categories))
+	next := Day(ref).AddDate(0, 0, 1) // allow one day of clock skew upstream
+
+	for i := len(categories) - 1; i >= 0; i-- {
+		day, month, err := parseCategory(categories[i])
+		if err != nil {
+			return nil, err
+		}
+
+		year := next.Year()
+		date, ok := makeDate(year, month, day)
+		for !ok || date.After(next) {
+			year--
+			if year < next.Year()-4 { // 29/02 needs up to four years
+				return nil, fmt.Errorf("cannot place chart date %q before %s", categories[i], next.Format(DateLayout))
+			}
+			date, ok = makeDate(year, month, day)
+		}
+
+		dates[i] = date
+		next = date
+	}
+	return dates, nil
+}
+
+func parseCategory(category string) (day, month int, err error) {
+	if _, err := fmt.Sscanf(category, "%d/%d", &day, &month); err != nil {
+		return 0, 0, fmt.Errorf("invalid chart date %q: %w", category, err)
+	}
+	if month < 1 || month > 12 || day < 1 || day > 31 {
+		return 0, 0, fmt.Errorf("invalid chart date %q", category)
+	}
+	return day, month, nil
+}
+
+// makeDate builds the date and reports false if it does not exist in year.
+func makeDate(year, month, day int) (time.Time, bool) {
+	date := time.Date(year, time.Month(month), day, 0, 0, 0, 0, Location)
+	return date, date.Day() == day
+}
@@ -0,0 +1,57 @@
+package vntime
+
+import (
+	"testing"
+	"time"
+)
+
+func TestResolveCategories(t *testing.T) {
+	at := func(year, month, day, hour int) time.Time {
+		return time.Date(year, time.Month(month), day, hour, 0, 0, 0, Location)
+	}
+
+	tests := []struct {
+		name       string
+		categories []string
+		ref        time.Time
+		want       []string
+		wantErr    bool
+	}{
+		{"same year", []string{"14/03", "15/03", "16/03"}, at(2025, 3, 16, 9), []string{"2025-03-14", "2025-03-15", "2025-03-16"}, false},
+		{"across New Year", []string{"30/12", "31/12", "01/01", "02/01"}, at(2026, 1, 2, 9), []string{"2025-12-30", "2025-12-31", "2026-01-01", "2026-01-02"}, false},
+		{"one day of skew", []string{"31/12", "01/01"}, at(2025, 12, 31, 23), []string{"2025-12-31", "2026-01-01"}, false},
+		{"last day a year ago", []string{"20/06"}, at(2025, 6, 18, 9), []string{"2024-06-20"}, false},
+		{"ref given in UTC", []string{"01/01"}, time.Date(2025, 12, 31, 18, 0, 0, 0, time.UTC), []string{"2026-01-01"}, false},
+		{"leap day", []string{"28/02", "29/02", "01/03"}, at(2024, 3, 1, 9), []string{"2024-02-28", "2024-02-29", "2024-03-01"}, false},
+		{"full year", []string{"17/03", "16/03"}, at(2025, 3, 16, 9), []string{"2024-03-17", "2025-03-16"}, false},
+		{"bad month", []string{"01/13"}, at(2025, 3, 16, 9), nil, true},
+		{"not a date", []string{"hôm nay"}, at(2025, 3, 16, 9), nil, true},
+		{"leap day in a later year", []string{"29/02"}, at(2026, 3, 1, 9), []string{"2024-02-29"}, false},
+		{"day missing from every year", []string{"31/02"}, at(2025, 3, 16, 9), nil, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dates, err := ResolveCategories(tt.categories, tt.ref)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatalf("got %v, want an error", dates)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(dates) != len(tt.want) {
+				t.Fatalf("got %d dates, want %d", len(dates), len(tt.want))
+			}
+			for i, date := range dates {
+				if got := date.Format(DateLayout); got != tt.want[i] {
+					t.Errorf("%s resolved to %s, want %s", tt.categories[i], got, tt.want[i])
+				}
+				if date.Location() != Location {
+					t.Errorf("%s resolved in %v, want %v", tt.categories[i], date.Location(), Location)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,349 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+const (
+	wsWriteWait   = 10 * time.Second
+	wsPongWait    = 60 * time.Second
+	wsPingPeriod  = wsPongWait * 9 / 10
+	wsMaxMessage  = 4096 // bytes per client message
+	wsSendBuffer  = 32   // messages queued per client before it is dropped
+	wsMaxChannels = 64
+)
+
+// WebSocket channels. The price and alert channels take an optional
+// ":<gold type>" suffix; without it they carry every gold type.
+const (
+	wsChannelPrices  = "prices"  // price.updated
+	wsChannelAlerts  = "alerts"  // alert.fired
+	wsChannelCrawls  = "crawls"  // crawl.completed
+	wsChannelSources = "sources" // source.down
+)
+
+// wsRequest is a message from a client:
+//
+//	{"action": "subscribe", "channels": ["prices:sjc", "alerts", "sources"]}
+//	{"action": "unsubscribe", "channels": ["prices:sjc"]}
+type wsRequest struct {
+	Action   string   `json:"action"`
+	Channels []string `json:"channels"`
+}
+
+// wsReply answers every request with the channels the client is now
+// subscribed to, or an error.
+type wsReply struct {
+	Type     string   `json:"type"` // subscribed or error
+	Channels []string `json:"channels"`
+	Error    string   `json:"error,omitempty"`
+}
+
+// wsHub serves GET /api/ws. Each connection subscribes to channels and
+// receives the matching events as the same JSON the webhooks get. Like the
+// SSE stream it is a Notifier fed by the NotifierHub.
+type wsHub struct {
+	upgrader websocket.Upgrader
+
+	mu      sync.Mutex
+	clients map[*wsClient]struct{}
+	closed  bool
+}
+
+type wsClient struct {
+	conn *websocket.Conn
+	send chan []byte // closed by the hub when the client is removed
+
+	mu       sync.Mutex
+	channels map[string]bool
+}
+
+func newWSHub() *wsHub {
+	return &wsHub{
+		upgrader: websocket.Upgrader{
+			// Same policy as the REST API: any origin.
+			CheckOrigin: func(r *http.Request) bool { return true },
+		},
+		clients: make(map[*wsClient]struct{}),
+	}
+}
+
+func (h *wsHub) Name() string {
+	return "websocket"
+}
+
+// eventChannels lists the channels ev is delivered on.
+func eventChannels(ev Event) []string {
+	switch {
+	case ev.Type == eventPriceUpdated && ev.Price != nil:
+		return []string{wsChannelPrices, wsChannelPrices + ":" + ev.Price.GoldType}
+	case ev.Type == eventAlertFired && ev.Alert != nil:
+		return []string{wsChannelAlerts, wsChannelAlerts + ":" + ev.Alert.GoldType}
+	case ev.Type == eventCrawlCompleted:
+		return []string{wsChannelCrawls}
+	case ev.Type == eventSourceDown:
+		return []string{wsChannelSources}
+	}
+	return nil
+}
+
+func (h *wsHub) Notify(ctx context.Context, ev Event) error {
+	channels := eventChannels(ev)
+	if len(channels) == 0 {
+		return nil
+	}
+	data, err := json.Marshal(ev)
+	if err != nil {
+		return err
+	}
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	for c := range h.clients {
+		if c.subscribed(channels) {
+			h.enqueueLocked(c, data)
+		}
+	}
+	return nil
+}
+
+func (c *wsClient) subscribed(channels []string) bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	for _, ch := range channels {
+		if c.channels[ch] {
+			return true
+		}
+	}
+	return false
+}
+
+// enqueueLocked queues data for c, dropping a client that cannot keep up.
+// h.mu must be held.
+func (h *wsHub) enqueueLocked(c *wsClient, data []byte) {
+	if _, ok := h.clients[c]; !ok {
+		return
+	}
+	select {
+	case c.send <- data:
+	default:
+		log.Printf("WebSocket client %s is too slow, disconnecting", c.conn.RemoteAddr())
+		delete(h.clients, c)
+		close(c.send)
+	}
+}
+
+func (h *wsHub) reply(c *wsClient, reply wsReply) {
+	data, err := json.Marshal(reply)
+	if err != nil {
+		return
+	}
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.enqueueLocked(c, data)
+}
+
+func (h *wsHub) remove(c *wsClient) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if _, ok := h.clients[c]; ok {
+		delete(h.clients, c)
+		close(c.send)
+	}
+}
+
+// Close disconnects every client. Hijacked connections are not closed by
+// http.Server.Shutdown.
+func (h *wsHub) Close() {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	h.closed = true
+	for c := range h.clients {
+		delete(h.clients, c)
+		close(c.send)
+	}
+}
+
+// validateChannel checks a channel name and the gold type it may name.
+func validateChannel(channel string) error {
+	name, goldType, hasType := strings.Cut(channel, ":")
+	switch name {
+	case wsChannelPrices, wsChannelAlerts:
+		if hasType {
+			if _, ok := enabledProvider(goldType); !ok {
+				return fmt.Errorf("unknown gold type %q", goldType)
+			}
+		}
+		return nil
+	case wsChannelCrawls, wsChannelSources:
+		if hasType {
+			return fmt.Errorf("channel %q takes no gold type", name)
+		}
+		return nil
+	}
+	return fmt.Errorf("unknown channel %q", channel)
+}
+
+// update applies a request and returns the resulting subscriptions.
+func (c *wsClient) update(req wsRequest) ([]string, error) {
+	if req.Action != "subscribe" && req.Action != "unsubscribe" {
+		return nil, fmt.Errorf("unknown action %q, want subscribe or unsubscribe", req.Action)
+	}
+	for _, ch := range req.Channels {
+		if err := validateChannel(ch); err != nil {
+			return nil, err
+		}
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if req.Action == "unsubscribe" {
+		for _, ch := range req.Channels {
+			delete(c.channels, ch)
+		}
+		return c.subscriptionsLocked(), nil
+	}
+
+	added := 0
+	for _, ch := range req.Channels {
+		if !c.channels[ch] {
+			added++
+		}
+	}
+	if len(c.channels)+added > wsMaxChannels {
+		return nil, fmt.Errorf("at most %d channels per connection", wsMaxChannels)
+	}
+	for _, ch := range req.Channels {
+		c.channels[ch] = true
+	}
+	return c.subscriptionsLocked(), nil
+}
+
+func (c *wsClient) subscriptions() []string {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.subscriptionsLocked()
+}
+
+func (c *wsClient) subscriptionsLocked() []string {
+	channels := make([]string, 0, len(c.channels))
+	for ch := range c.channels {
+		channels = append(channels, ch)
+	}
+	slices.Sort(channels)
+	return channels
+}
+
+// ServeHTTP upgrades GET /api/ws. Initial channels may be given as
+// ?channels=prices:sjc,alerts.
+func (h *wsHub) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	c := &wsClient{channels: make(map[string]bool)}
+	if v := r.URL.Query().Get("channels"); v != "" {
+		if _, err := c.update(wsRequest{Action: "subscribe", Channels: splitList(v)}); err != nil {
+			respondWithError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+	}
+
+	h.mu.Lock()
+	closed := h.closed
+	h.mu.Unlock()
+	if closed {
+		respondWithError(w, http.StatusServiceUnavailable, "Server is shutting down")
+		return
+	}
+
+	conn, err := h.upgrader.Upgrade(w, r, nil)
+	if err != nil {
+		return // the upgrader already replied
+	}
+	c.conn = conn
+	c.send = make(chan []byte, wsSendBuffer)
+
+	h.mu.Lock()
+	if h.closed {
+		h.mu.Unlock()
+		conn.Close()
+		return
+	}
+	h.clients[c] = struct{}{}
+	h.mu.Unlock()
+
+	go h.writePump(c)
+	h.reply(c, wsReply{Type: "subscribed", Channels: c.subscriptions()})
+	h.readPump(c)
+}
+
+// readPump handles requests until the connection fails, then removes the
+// client.
+func (h *wsHub) readPump(c *wsClient) {
+	defer h.remove(c)
+
+	c.conn.SetReadLimit(wsMaxMessage)
+	c.conn.SetReadDeadline(time.Now().Add(wsPongWait))
+	c.conn.SetPongHandler(func(string) error {
+		return c.conn.SetReadDeadline(time.Now().Add(wsPongWait))
+	})
+
+	for {
+		_, data, err := c.conn.ReadMessage()
+		if err != nil {
+			return
+		}
+		var req wsRequest
+		if err := json.Unmarshal(data, &req); err != nil {
+			h.reply(c, wsReply{Type: "error", Channels: c.subscriptions(), Error: "invalid JSON request"})
+			continue
+		}
+		channels, err := c.update(req)
+		if err != nil {
+			h.reply(c, wsReply{Type: "error", Channels: c.subscriptions(), Error: err.Error()})
+			continue
+		}
+		h.reply(c, wsReply{Type: "subscribed", Channels: channels})
+	}
+}
+
+// writePump is the only writer of the connection. It sends queued messages
+// and pings, and closes the connection once the client is removed.
+func (h *wsHub) writePump(c *wsClient) {
+	ticker := time.NewTicker(wsPingPeriod)
+	defer func() {
+		ticker.Stop()
+		c.conn.Close()
+	}()
+
+	for {
+		select {
+		case data, ok := <-c.send:
+			c.conn.SetWriteDeadline(time.Now().Add(wsWriteWait))
+			if !ok {
+				c.conn.WriteMessage(websocket.CloseMessage, websocket.FormatCloseMessage(websocket.CloseGoingAway, ""))
+				return
+			}
+			if err := c.conn.WriteMessage(websocket.TextMessage, data); err != nil {
+				return
+			}
+		case <-ticker.C:
+			c.conn.SetWriteDeadline(time.Now().Add(wsWriteWait))
+			if err := c.conn.WriteMessage(websocket.PingMessage, nil); err != nil {
+				return
+			}
+		}
+	}
+}
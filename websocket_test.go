@@ -0,0 +1,163 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+// dialWS connects to the hub behind srv and reads the initial subscribed
+// reply, after which the client is registered.
+func dialWS(t *testing.T, srv *httptest.Server, query string) (*websocket.Conn, wsReply) {
+	t.Helper()
+	url := "ws" + strings.TrimPrefix(srv.URL, "http") + query
+	conn, _, err := websocket.DefaultDialer.Dial(url, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { conn.Close() })
+	return conn, readWSReply(t, conn)
+}
+
+func readWS(t *testing.T, conn *websocket.Conn, v any) {
+	t.Helper()
+	conn.SetReadDeadline(time.Now().Add(2 * time.Second))
+	if err := conn.ReadJSON(v); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func readWSReply(t *testing.T, conn *websocket.Conn) wsReply {
+	t.Helper()
+	var reply wsReply
+	readWS(t, conn, &reply)
+	return reply
+}
+
+func TestWebSocketChannels(t *testing.T) {
+	hub := newWSHub()
+	srv := httptest.NewServer(hub)
+	defer srv.Close()
+	defer hub.Close()
+
+	prices, reply := dialWS(t, srv, "?channels=prices:sjc")
+	if reply.Type != "subscribed" || !slices.Equal(reply.Channels, []string{"prices:sjc"}) {
+		t.Fatalf("initial reply = %+v", reply)
+	}
+	everything, _ := dialWS(t, srv, "")
+	if err := everything.WriteJSON(wsRequest{Action: "subscribe", Channels: []string{"prices", "alerts", "crawls", "sources"}}); err != nil {
+		t.Fatal(err)
+	}
+	if reply := readWSReply(t, everything); reply.Type != "subscribed" || len(reply.Channels) != 4 {
+		t.Fatalf("subscribe reply = %+v", reply)
+	}
+
+	alert := newEvent(eventAlertFired)
+	alert.Alert = &AlertEvent{GoldType: "doji_hn", Kind: alertAbove}
+	for _, ev := range []Event{priceEvent("doji_hn", 119e6), priceEvent("sjc", 121e6), alert, newEvent(eventCrawlCompleted), newEvent(eventDigestDaily)} {
+		if err := hub.Notify(context.Background(), ev); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	var got Event
+	readWS(t, prices, &got)
+	if got.Type != eventPriceUpdated || got.Price.GoldType != "sjc" {
+		t.Errorf("prices:sjc got %s %+v, want the sjc price", got.Type, got.Price)
+	}
+	var types []string
+	for range 4 {
+		var ev Event
+		readWS(t, everything, &ev)
+		types = append(types, ev.Type)
+	}
+	want := []string{eventPriceUpdated, eventPriceUpdated, eventAlertFired, eventCrawlCompleted}
+	if !slices.Equal(types, want) {
+		t.Errorf("every channel got %v, want %v", types, want)
+	}
+
+	// After unsubscribing, the next message is the reply to another request
+	// rather than the sjc price.
+	prices.WriteJSON(wsRequest{Action: "unsubscribe", Channels: []string{"prices:sjc"}})
+	if reply := readWSReply(t, prices); reply.Type != "subscribed" || len(reply.Channels) != 0 {
+		t.Fatalf("unsubscribe reply = %+v", reply)
+	}
+	hub.Notify(context.Background(), priceEvent("sjc", 122e6))
+	prices.WriteJSON(wsRequest{Action: "subscribe", Channels: []string{"crawls"}})
+	if reply := readWSReply(t, prices); !slices.Equal(reply.Channels, []string{"crawls"}) {
+		t.Errorf("reply after the price event = %+v, want the price dropped", reply)
+	}
+}
+
+func TestWebSocketRequestErrors(t *testing.T) {
+	hub := newWSHub()
+	srv := httptest.NewServer(hub)
+	defer srv.Close()
+	defer hub.Close()
+
+	conn, _ := dialWS(t, srv, "?channels=alerts")
+	tests := []struct {
+		name    string
+		request string
+		wantErr string
+	}{
+		{"unknown channel", `{"action": "subscribe", "channels": ["weather"]}`, `unknown channel "weather"`},
+		{"unknown gold type", `{"action": "subscribe", "channels": ["prices:vang_gia"]}`, `unknown gold type "vang_gia"`},
+		{"gold type on crawls", `{"action": "subscribe", "channels": ["crawls:sjc"]}`, "takes no gold type"},
+		{"unknown action", `{"action": "listen", "channels": ["prices"]}`, `unknown action "listen"`},
+		{"invalid JSON", `{"action": `, "invalid JSON request"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := conn.WriteMessage(websocket.TextMessage, []byte(tt.request)); err != nil {
+				t.Fatal(err)
+			}
+			reply := readWSReply(t, conn)
+			if reply.Type != "error" || !strings.Contains(reply.Error, tt.wantErr) {
+				t.Errorf("reply = %+v, want an error about %s", reply, tt.wantErr)
+			}
+			if !slices.Equal(reply.Channels, []string{"alerts"}) {
+				t.Errorf("channels after a failed request = %v, want [alerts]", reply.Channels)
+			}
+		})
+	}
+}
+
+func TestWebSocketRejectsBadChannelsBeforeUpgrading(t *testing.T) {
+	hub := newWSHub()
+	srv := httptest.NewServer(hub)
+	defer srv.Close()
+
+	url := "ws" + strings.TrimPrefix(srv.URL, "http") + "?channels=prices,weather"
+	_, resp, err := websocket.DefaultDialer.Dial(url, nil)
+	if err == nil || resp == nil || resp.StatusCode != http.StatusBadRequest {
+		t.Fatalf("dial = %v, %+v; want 400", err, resp)
+	}
+	var body map[string]string
+	json.NewDecoder(resp.Body).Decode(&body)
+	if !strings.Contains(body["error"], "weather") {
+		t.Errorf("error body = %v", body)
+	}
+}
+
+func TestWebSocketClose(t *testing.T) {
+	hub := newWSHub()
+	srv := httptest.NewServer(hub)
+	defer srv.Close()
+
+	conn, _ := dialWS(t, srv, "?channels=prices")
+	hub.Close()
+
+	conn.SetReadDeadline(time.Now().Add(2 * time.Second))
+	_, _, err := conn.ReadMessage()
+	if !websocket.IsCloseError(err, websocket.CloseGoingAway) {
+		t.Errorf("read after Close = %v, want a going-away close", err)
+	}
+}